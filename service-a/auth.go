@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Assinaturas já utilizadas dentro da janela, para rejeitar reenvios
+var (
+	seenSignaturesMu sync.Mutex
+	seenSignatures   = make(map[string]time.Time)
+)
+
+// signBody calcula a assinatura esperada: HMAC-SHA256 de "<timestamp>.<corpo>"
+func signBody(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyHMAC valida o cabeçalho "Authorization: HMAC <timestamp>:<assinatura>"
+// contra o corpo da requisição. O timestamp é aceito até window antes ou
+// depois de now, tolerando a diferença entre os relógios do cliente e do
+// servidor.
+func verifyHMAC(secret, header string, body []byte, now time.Time, window time.Duration) bool {
+	credentials, ok := strings.CutPrefix(header, "HMAC ")
+	if !ok {
+		return false
+	}
+	timestamp, signature, ok := strings.Cut(credentials, ":")
+	if !ok {
+		return false
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	sent := time.Unix(ts, 0)
+	if now.Sub(sent).Abs() > window {
+		return false
+	}
+
+	expected := signBody(secret, timestamp, body)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return false
+	}
+
+	seenSignaturesMu.Lock()
+	defer seenSignaturesMu.Unlock()
+	for sig, expiry := range seenSignatures {
+		if now.After(expiry) {
+			delete(seenSignatures, sig)
+		}
+	}
+	if _, replayed := seenSignatures[signature]; replayed {
+		return false
+	}
+	seenSignatures[signature] = sent.Add(window)
+	return true
+}
+
+// requireHMAC exige assinatura HMAC válida quando AUTH_HMAC_SECRET está definido
+func requireHMAC(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.AuthHMACSecret == "" {
+			next(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeBodyError(w, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		if !verifyHMAC(cfg.AuthHMACSecret, r.Header.Get("Authorization"), body, time.Now(), time.Duration(cfg.AuthSkewSeconds)*time.Second) {
+			writeJSONError(w, http.StatusUnauthorized, "invalid signature", "invalid_signature")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// apiKey associa uma chave aceita à identidade registrada nos spans, para que
+// a chave em si nunca seja exportada
+type apiKey struct {
+	id  string
+	key string
+}
+
+var apiKeys []apiKey
+
+// Erro encontrado ao carregar a configuração de autenticação; enquanto estiver
+// definido, as requisições seguem AUTH_FAIL_MODE
+var authConfigErr error
+
+type apiKeyCtxKey struct{}
+
+// parseAPIKeys interpreta os itens de API_KEYS, no formato "<identidade>:<chave>"
+// ou apenas "<chave>"; sem identidade explícita, usa um prefixo do hash da chave
+func parseAPIKeys(entries []string) ([]apiKey, error) {
+	keys := make([]apiKey, 0, len(entries))
+	for i, entry := range entries {
+		id, key, ok := strings.Cut(entry, ":")
+		if !ok {
+			sum := sha256.Sum256([]byte(entry))
+			id, key = "key-"+hex.EncodeToString(sum[:])[:8], entry
+		}
+		if id == "" || key == "" {
+			return nil, fmt.Errorf("malformed api key entry at position %d", i+1)
+		}
+		keys = append(keys, apiKey{id: id, key: key})
+	}
+	return keys, nil
+}
+
+func lookupAPIKey(key string) (string, bool) {
+	for _, k := range apiKeys {
+		if subtle.ConstantTimeCompare([]byte(k.key), []byte(key)) == 1 {
+			return k.id, true
+		}
+	}
+	return "", false
+}
+
+// apiKeyID devolve a identidade da chave autenticada na requisição, se houver
+func apiKeyID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(apiKeyCtxKey{}).(string)
+	return id, ok
+}
+
+// requireAPIKey exige um X-API-Key válido quando API_KEYS está definido
+func requireAPIKey(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if authConfigErr != nil {
+			authUnavailable(w, r, next)
+			return
+		}
+		if len(apiKeys) == 0 {
+			next(w, r)
+			return
+		}
+
+		id, ok := lookupAPIKey(r.Header.Get("X-API-Key"))
+		if !ok {
+			writeJSONError(w, http.StatusUnauthorized, "invalid api key", "invalid_api_key")
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), apiKeyCtxKey{}, id)))
+	}
+}
+
+// authUnavailable trata uma requisição cuja autenticação não pôde ser avaliada:
+// com AUTH_FAIL_MODE=open ela segue sem autenticação, com closed recebe 503
+func authUnavailable(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if cfg.AuthFailMode == "open" {
+		slog.WarnContext(r.Context(), "Authentication unavailable, allowing request (fail-open)", "error", authConfigErr)
+		next(w, r)
+		return
+	}
+	writeJSONError(w, http.StatusServiceUnavailable, "authentication unavailable", "auth_unavailable")
+}
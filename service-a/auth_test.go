@@ -0,0 +1,255 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testHMACSecret = "s3cret"
+
+// postSignedCEP envia o corpo a POST /cep com o cabeçalho Authorization
+// informado
+func postSignedCEP(t *testing.T, body, authorization string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/cep", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	if authorization != "" {
+		r.Header.Set("Authorization", authorization)
+	}
+	return serve(r)
+}
+
+// hmacAuthorization assina o corpo com o instante informado, como um cliente
+func hmacAuthorization(body string, at time.Time) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return fmt.Sprintf("HMAC %s:%s", timestamp, signBody(testHMACSecret, timestamp, []byte(body)))
+}
+
+func TestHMACAuth(t *testing.T) {
+	const body = `{"cep": "01001000"}`
+	tests := []struct {
+		name          string
+		body          string
+		authorization string
+		wantStatus    int
+	}{
+		{"valid", body, hmacAuthorization(body, time.Now()), http.StatusOK},
+		{"missing", body, "", http.StatusUnauthorized},
+		{"expired", body, hmacAuthorization(body, time.Now().Add(-10*time.Minute)), http.StatusUnauthorized},
+		{"tampered body", `{"cep": "20040020"}`, hmacAuthorization(body, time.Now()), http.StatusUnauthorized},
+		{"wrong secret", body, "HMAC " + strconv.FormatInt(time.Now().Unix(), 10) + ":" + signBody("other", strconv.FormatInt(time.Now().Unix(), 10), []byte(body)), http.StatusUnauthorized},
+		{"malformed", body, "Bearer token", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := setupWithServiceB(t, http.StatusOK, serviceBTemperature, "AUTH_HMAC_SECRET", testHMACSecret)
+
+			rec := postSignedCEP(t, tt.body, tt.authorization)
+			if tt.wantStatus != http.StatusOK {
+				assertError(t, rec, tt.wantStatus, "invalid_signature")
+				if n := len(stub.requests()); n != 0 {
+					t.Errorf("rejected request reached Service B %d times", n)
+				}
+				return
+			}
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+			}
+			// O corpo lido para a verificação chega intacto ao Service B
+			if reqs := stub.requests(); len(reqs) != 1 || !strings.Contains(reqs[0].body, "01001000") {
+				t.Errorf("Service B received %+v, want the signed CEP", reqs)
+			}
+		})
+	}
+}
+
+func TestHMACAuthRejectsReplay(t *testing.T) {
+	setupWithServiceB(t, http.StatusOK, serviceBTemperature, "AUTH_HMAC_SECRET", testHMACSecret)
+
+	const body = `{"cep": "01310100"}`
+	authorization := hmacAuthorization(body, time.Now())
+	if rec := postSignedCEP(t, body, authorization); rec.Code != http.StatusOK {
+		t.Fatalf("first request: status = %d (body %s)", rec.Code, rec.Body.String())
+	}
+	assertError(t, postSignedCEP(t, body, authorization), http.StatusUnauthorized, "invalid_signature")
+}
+
+func TestHMACAuthDisabled(t *testing.T) {
+	setupWithServiceB(t, http.StatusOK, serviceBTemperature)
+
+	if rec := postSignedCEP(t, `{"cep": "01001000"}`, ""); rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200 without AUTH_HMAC_SECRET (body %s)", rec.Code, rec.Body.String())
+	}
+}
+
+// postCEPWithKey envia um CEP a POST /cep com o X-API-Key informado
+func postCEPWithKey(t *testing.T, key string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/cep", strings.NewReader(`{"cep": "01001000"}`))
+	r.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		r.Header.Set("X-API-Key", key)
+	}
+	return serve(r)
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	// Sem identidade explícita, a chave é identificada pelo prefixo do hash
+	sum := sha256.Sum256([]byte("bare-key"))
+	bareKeyID := "key-" + hex.EncodeToString(sum[:])[:8]
+	const keys = "partner:partner-key,bare-key"
+	tests := []struct {
+		name   string
+		key    string
+		wantID string // vazio quando a requisição deve ser rejeitada
+	}{
+		{"named key", "partner-key", "partner"},
+		{"bare key", "bare-key", bareKeyID},
+		{"invalid", "guess", ""},
+		{"missing", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := setupWithServiceB(t, http.StatusOK, serviceBTemperature, "API_KEYS", keys)
+			spans := recordSpans(t)
+
+			rec := postCEPWithKey(t, tt.key)
+			if tt.wantID == "" {
+				assertError(t, rec, http.StatusUnauthorized, "invalid_api_key")
+				if n := len(stub.requests()); n != 0 {
+					t.Errorf("rejected request reached Service B %d times", n)
+				}
+				return
+			}
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+			}
+			span := findSpan(t, spans.Ended(), "handleCEP")
+			if id, ok := spanAttribute(span, "auth.api_key_id"); !ok || id.AsString() != tt.wantID {
+				t.Errorf("auth.api_key_id = %q, want %q", id.Emit(), tt.wantID)
+			}
+			for _, kv := range span.Attributes() {
+				if strings.Contains(kv.Value.Emit(), tt.key) {
+					t.Errorf("span attribute %s exposes the key", kv.Key)
+				}
+			}
+		})
+	}
+}
+
+func TestAPIKeyAuthDisabled(t *testing.T) {
+	setupWithServiceB(t, http.StatusOK, serviceBTemperature)
+
+	for _, key := range []string{"", "anything"} {
+		if rec := postCEPWithKey(t, key); rec.Code != http.StatusOK {
+			t.Errorf("X-API-Key %q: status = %d, want 200 without API_KEYS (body %s)", key, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestParseAPIKeysRejectsMalformedEntries(t *testing.T) {
+	for _, entry := range []string{":key", "partner:"} {
+		if _, err := parseAPIKeys([]string{"ok-key", entry}); err == nil || !strings.Contains(err.Error(), "position 2") {
+			t.Errorf("parseAPIKeys(%q) error = %v, want malformed entry at position 2", entry, err)
+		}
+	}
+}
+
+func TestAuthFailMode(t *testing.T) {
+	for _, tc := range []struct {
+		mode       string
+		wantStatus int
+	}{
+		{"", http.StatusServiceUnavailable},
+		{"closed", http.StatusServiceUnavailable},
+		{"open", http.StatusOK},
+	} {
+		t.Run("AUTH_FAIL_MODE="+tc.mode, func(t *testing.T) {
+			// Entrada malformada em API_KEYS: a autenticação não pode ser avaliada
+			stub := setupWithServiceB(t, http.StatusOK, serviceBTemperature,
+				"API_KEYS", "partner:",
+				"AUTH_FAIL_MODE", tc.mode,
+			)
+			if authConfigErr == nil {
+				t.Fatal("authConfigErr = nil, want the malformed API_KEYS error")
+			}
+
+			rec := postCEPWithKey(t, "anything")
+			if tc.wantStatus != http.StatusOK {
+				assertError(t, rec, tc.wantStatus, "auth_unavailable")
+				if n := len(stub.requests()); n != 0 {
+					t.Errorf("rejected request reached Service B %d times", n)
+				}
+				return
+			}
+			if rec.Code != http.StatusOK || len(stub.requests()) != 1 {
+				t.Errorf("status = %d with %d Service B calls, want 200 and the request forwarded", rec.Code, len(stub.requests()))
+			}
+		})
+	}
+}
+
+func TestHMACAuthSkewWindow(t *testing.T) {
+	const body = `{"cep": "01001000"}`
+	tests := []struct {
+		name       string
+		skew       string
+		offset     time.Duration
+		wantStatus int
+	}{
+		{"in window", "60", -30 * time.Second, http.StatusOK},
+		{"future within skew", "60", 30 * time.Second, http.StatusOK},
+		{"future beyond skew", "60", 90 * time.Second, http.StatusUnauthorized},
+		{"expired", "60", -90 * time.Second, http.StatusUnauthorized},
+		// Padrão de 300s
+		{"default window", "", 4 * time.Minute, http.StatusOK},
+		{"default window expired", "", -6 * time.Minute, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := []string{"AUTH_HMAC_SECRET", testHMACSecret}
+			if tt.skew != "" {
+				env = append(env, "AUTH_SKEW_SECONDS", tt.skew)
+			}
+			setupWithServiceB(t, http.StatusOK, serviceBTemperature, env...)
+
+			rec := postSignedCEP(t, body, hmacAuthorization(body, time.Now().Add(tt.offset)))
+			if tt.wantStatus != http.StatusOK {
+				assertError(t, rec, tt.wantStatus, "invalid_signature")
+				return
+			}
+			if rec.Code != http.StatusOK {
+				t.Errorf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestVerifyHMACWindowBounds(t *testing.T) {
+	setupTest(t)
+	body := []byte(`{"cep": "01001000"}`)
+	now := time.Unix(1_700_000_000, 0)
+	window := time.Minute
+
+	for _, tc := range []struct {
+		offset time.Duration
+		want   bool
+	}{
+		{-window, true},
+		{window, true},
+		{-window - time.Second, false},
+		{window + time.Second, false},
+	} {
+		header := hmacAuthorization(string(body), now.Add(tc.offset))
+		if got := verifyHMAC(testHMACSecret, header, body, now, window); got != tc.want {
+			t.Errorf("verifyHMAC(offset %v) = %v, want %v", tc.offset, got, tc.want)
+		}
+	}
+}
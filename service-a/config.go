@@ -0,0 +1,233 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config centraliza as configurações do serviço. Cada campo é lido da
+// variável de ambiente indicada na tag `env`, usando `default` quando ela
+// não está definida, e verificado pelas regras da tag `validate`
+// (required, min=N, max=N, oneof=a b c).
+type Config struct {
+	Port                    string   `env:"PORT" default:"8080" validate:"required"`
+	ServiceBURL             string   `env:"SERVICE_B_URL" default:"http://service-b:8081/temperature" validate:"required"`
+	MetricsAddr             string   `env:"METRICS_ADDR"`
+	HealthStatusCode        int      `env:"HEALTH_STATUS_CODE" default:"200" validate:"oneof=200 204"`
+	ZipkinEndpoint          string   `env:"OTEL_EXPORTER_ZIPKIN_ENDPOINT"`
+	TraceVerbosity          string   `env:"TRACE_VERBOSITY" default:"full" validate:"oneof=full minimal"`
+	TraceExporters          []string `env:"TRACE_EXPORTERS" default:"zipkin" validate:"required,oneof=zipkin otlp stdout"`
+	OTLPProtocol            string   `env:"OTEL_EXPORTER_OTLP_PROTOCOL" default:"grpc" validate:"oneof=grpc http/protobuf"`
+	TraceAttributeMaxLength int      `env:"TRACE_ATTRIBUTE_MAX_LENGTH" default:"256" validate:"min=1"`
+
+	SlowRequestThresholdMS int    `env:"SLOW_REQUEST_THRESHOLD_MS" default:"2000" validate:"min=0"`
+	LogLevel               string `env:"LOG_LEVEL" default:"info" validate:"oneof=debug info warn error"`
+
+	DeployEnv           string `env:"DEPLOY_ENV" default:"development" validate:"required"`
+	DeployRegion        string `env:"DEPLOY_REGION"`
+	DeployZone          string `env:"DEPLOY_ZONE"`
+	TracingProfilesFile string `env:"TRACING_PROFILES_FILE"`
+	TracesSamplerArg    string `env:"OTEL_TRACES_SAMPLER_ARG"`
+
+	ValidateContentLength    bool          `env:"VALIDATE_CONTENT_LENGTH" default:"false"`
+	MaxBodyBytes             int64         `env:"MAX_BODY_BYTES" default:"1048576" validate:"min=1"`
+	UpstreamDisableKeepAlive bool          `env:"UPSTREAM_DISABLE_KEEPALIVE" default:"false"`
+	HTTPClientTimeout        time.Duration `env:"HTTP_CLIENT_TIMEOUT" default:"10s" validate:"min=1ms"`
+	RequestTimeout           time.Duration `env:"REQUEST_TIMEOUT" default:"10s" validate:"min=1ms"`
+	ShutdownGracePeriod      time.Duration `env:"SHUTDOWN_GRACE_PERIOD" default:"10s" validate:"min=0s"`
+
+	CEPFieldName string `env:"CEP_FIELD_NAME" default:"cep" validate:"required"`
+	SoftErrors   bool   `env:"SOFT_ERRORS" default:"false"`
+	AcceptCity   bool   `env:"ACCEPT_CITY" default:"false"`
+
+	OpenAPIEnabled bool `env:"OPENAPI_ENABLED" default:"false"`
+	ProbeEnabled   bool `env:"PROBE_ENABLED" default:"false"`
+
+	AuthHMACSecret  string   `env:"AUTH_HMAC_SECRET"`
+	APIKeys         []string `env:"API_KEYS"`
+	AuthFailMode    string   `env:"AUTH_FAIL_MODE" default:"closed" validate:"oneof=open closed"`
+	AuthSkewSeconds int      `env:"AUTH_SKEW_SECONDS" default:"300" validate:"min=1"`
+
+	RateLimitRPS      float64 `env:"RATE_LIMIT_RPS" default:"0" validate:"min=0"`
+	RateLimitBurst    int     `env:"RATE_LIMIT_BURST" default:"10" validate:"min=1"`
+	TrustForwardedFor bool    `env:"TRUST_FORWARDED_FOR" default:"false"`
+}
+
+var cfg Config
+
+func loadConfig() (Config, error) {
+	var c Config
+	if err := bindEnv(&c); err != nil {
+		return Config{}, err
+	}
+	return c, nil
+}
+
+// bindEnv preenche os campos de target (ponteiro para struct) a partir das
+// variáveis de ambiente e acumula todos os erros de conversão e validação
+func bindEnv(target any) error {
+	v := reflect.ValueOf(target).Elem()
+	t := v.Type()
+
+	var errs []error
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("env")
+		if name == "" {
+			continue
+		}
+
+		raw := os.Getenv(name)
+		if raw == "" {
+			raw = field.Tag.Get("default")
+		}
+
+		if err := setField(v.Field(i), raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		if err := validateField(v.Field(i), field.Tag.Get("validate")); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func setField(f reflect.Value, raw string) error {
+	if f.Type() == reflect.TypeOf(time.Duration(0)) {
+		if raw == "" {
+			return nil
+		}
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		f.SetInt(int64(d))
+		return nil
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(raw)
+	case reflect.Bool:
+		if raw == "" {
+			return nil
+		}
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		if raw == "" {
+			return nil
+		}
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		f.SetInt(n)
+	case reflect.Float64:
+		if raw == "" {
+			return nil
+		}
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		f.SetFloat(n)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		f.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported field type %s", f.Type())
+	}
+	return nil
+}
+
+func validateField(f reflect.Value, rules string) error {
+	if rules == "" {
+		return nil
+	}
+
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			if f.IsZero() || (f.Kind() == reflect.Slice && f.Len() == 0) {
+				return fmt.Errorf("value is required")
+			}
+		case "oneof":
+			// Em listas, a regra vale para cada item
+			values := []string{fmt.Sprint(f.Interface())}
+			if f.Kind() == reflect.Slice {
+				values = f.Interface().([]string)
+			}
+			options := strings.Fields(arg)
+			for _, value := range values {
+				if !contains(options, value) {
+					return fmt.Errorf("value %q must be one of %s", value, strings.Join(options, ", "))
+				}
+			}
+		case "min", "max":
+			limit, value, err := numericBound(f, arg)
+			if err != nil {
+				return err
+			}
+			if name == "min" && value < limit {
+				return fmt.Errorf("value must be >= %s", arg)
+			}
+			if name == "max" && value > limit {
+				return fmt.Errorf("value must be <= %s", arg)
+			}
+		default:
+			return fmt.Errorf("unknown validation rule %q", name)
+		}
+	}
+	return nil
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// numericBound converte o limite da regra e o valor do campo para float64,
+// tratando durações no formato do time.ParseDuration
+func numericBound(f reflect.Value, arg string) (limit, value float64, err error) {
+	if f.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(arg)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid duration bound %q", arg)
+		}
+		return float64(d), float64(f.Int()), nil
+	}
+
+	limit, err = strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid numeric bound %q", arg)
+	}
+	switch f.Kind() {
+	case reflect.Int, reflect.Int64:
+		return limit, float64(f.Int()), nil
+	case reflect.Float64:
+		return limit, f.Float(), nil
+	case reflect.String, reflect.Slice:
+		return limit, float64(f.Len()), nil
+	}
+	return 0, 0, fmt.Errorf("min/max not supported for %s", f.Type())
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfigDefaults(t *testing.T) {
+	c, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	if c.Port != "8080" || c.ServiceBURL != "http://service-b:8081/temperature" {
+		t.Errorf("Port/ServiceBURL = %q/%q, want the defaults", c.Port, c.ServiceBURL)
+	}
+	if c.HTTPClientTimeout != 10*time.Second || c.RateLimitBurst != 10 || c.AuthFailMode != "closed" {
+		t.Errorf("HTTPClientTimeout/RateLimitBurst/AuthFailMode = %v/%d/%q, want 10s/10/closed",
+			c.HTTPClientTimeout, c.RateLimitBurst, c.AuthFailMode)
+	}
+	if !reflect.DeepEqual(c.TraceExporters, []string{"zipkin"}) || c.APIKeys != nil {
+		t.Errorf("TraceExporters/APIKeys = %v/%v, want [zipkin]/nil", c.TraceExporters, c.APIKeys)
+	}
+}
+
+func TestLoadConfigOverrides(t *testing.T) {
+	t.Setenv("PORT", "9090")
+	t.Setenv("HTTP_CLIENT_TIMEOUT", "750ms")
+	t.Setenv("RATE_LIMIT_RPS", "2.5")
+	t.Setenv("SOFT_ERRORS", "true")
+	t.Setenv("TRACE_EXPORTERS", "otlp, stdout")
+
+	c, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	if c.Port != "9090" || c.HTTPClientTimeout != 750*time.Millisecond || c.RateLimitRPS != 2.5 || !c.SoftErrors {
+		t.Errorf("config = %+v, want the overridden values", c)
+	}
+	if !reflect.DeepEqual(c.TraceExporters, []string{"otlp", "stdout"}) {
+		t.Errorf("TraceExporters = %v, want [otlp stdout]", c.TraceExporters)
+	}
+}
+
+func TestLoadConfigValidation(t *testing.T) {
+	tests := []struct {
+		env, value, want string
+	}{
+		{"HEALTH_STATUS_CODE", "500", "HEALTH_STATUS_CODE: value \"500\" must be one of 200, 204"},
+		{"AUTH_FAIL_MODE", "maybe", "AUTH_FAIL_MODE: value \"maybe\" must be one of open, closed"},
+		{"TRACE_EXPORTERS", "zipkin,jaeger", "TRACE_EXPORTERS: value \"jaeger\" must be one of zipkin, otlp, stdout"},
+		{"RATE_LIMIT_RPS", "-1", "RATE_LIMIT_RPS: value must be >= 0"},
+		{"HTTP_CLIENT_TIMEOUT", "0s", "HTTP_CLIENT_TIMEOUT: value must be >= 1ms"},
+		{"HTTP_CLIENT_TIMEOUT", "soon", "HTTP_CLIENT_TIMEOUT: invalid duration \"soon\""},
+		{"RATE_LIMIT_BURST", "ten", "RATE_LIMIT_BURST: invalid integer \"ten\""},
+		{"SOFT_ERRORS", "sim", "SOFT_ERRORS: invalid boolean \"sim\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env+"="+tt.value, func(t *testing.T) {
+			t.Setenv(tt.env, tt.value)
+			if _, err := loadConfig(); err == nil || err.Error() != tt.want {
+				t.Errorf("loadConfig error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigReportsAllErrors(t *testing.T) {
+	t.Setenv("AUTH_SKEW_SECONDS", "0")
+	t.Setenv("LOG_LEVEL", "verbose")
+
+	_, err := loadConfig()
+	if err == nil {
+		t.Fatal("loadConfig succeeded, want an error")
+	}
+	for _, name := range []string{"AUTH_SKEW_SECONDS", "LOG_LEVEL"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error %q does not mention %s", err, name)
+		}
+	}
+}
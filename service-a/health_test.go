@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthStatusCode(t *testing.T) {
+	for _, tc := range []struct {
+		env  string
+		want int
+	}{
+		{"", http.StatusOK},
+		{"200", http.StatusOK},
+		{"204", http.StatusNoContent},
+	} {
+		t.Run("HEALTH_STATUS_CODE="+tc.env, func(t *testing.T) {
+			setupTest(t, "HEALTH_STATUS_CODE", tc.env)
+
+			rec := serve(httptest.NewRequest(http.MethodGet, "/health", nil))
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tc.want)
+			}
+			if tc.want == http.StatusNoContent {
+				if rec.Body.Len() != 0 {
+					t.Errorf("body = %q, want none with 204", rec.Body.String())
+				}
+				return
+			}
+			if got := decodeBody[HealthResponse](t, rec); got != (HealthResponse{SchemaVersion: schemaVersion, Status: "ok"}) {
+				t.Errorf("body = %+v", got)
+			}
+		})
+	}
+}
+
+func TestHealthStatusCodeRejectsOtherValues(t *testing.T) {
+	t.Setenv("HEALTH_STATUS_CODE", "503")
+	if _, err := loadConfig(); err == nil {
+		t.Error("loadConfig accepted HEALTH_STATUS_CODE=503")
+	}
+}
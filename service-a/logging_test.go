@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestTraceHandlerAddsRequestCEPAndCity(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(traceHandler{slog.NewJSONHandler(&buf, nil)}))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	ctx := withCity(withCEP(context.Background(), "01001000"), "São Paulo")
+	slog.InfoContext(ctx, "plain log")
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("decoding log line %q: %v", buf.String(), err)
+	}
+	if entry["cep"] != "01001000" || entry["city"] != "São Paulo" {
+		t.Errorf("log %v, want cep 01001000 and city São Paulo", entry)
+	}
+}
@@ -4,56 +4,120 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
 	"go.opentelemetry.io/otel/exporters/zipkin"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type CEPRequest struct {
 	CEP string `json:"cep"`
+	// CEP como o cliente o informou, repassado ao Service B apenas quando a
+	// normalização o alterou, para que ele responda com requested_cep
+	RequestedCEP string `json:"requested_cep,omitempty"`
+	City         string `json:"city,omitempty"`
+}
+
+// Versão do formato das respostas de erro; acompanha a do Service B
+const schemaVersion = "1.1"
+
+// newExporter cria o exporter de spans correspondente a um item de TRACE_EXPORTERS
+func newExporter(name string, profile tracingProfile) (sdktrace.SpanExporter, error) {
+	switch name {
+	case "zipkin":
+		// OTEL_EXPORTER_ZIPKIN_ENDPOINT tem precedência sobre o perfil do ambiente
+		endpoint := cfg.ZipkinEndpoint
+		if endpoint == "" {
+			endpoint = profile.ZipkinEndpoint
+		}
+		exporter, err := zipkin.New(
+			endpoint,
+			zipkin.WithLogger(log.New(os.Stdout, "zipkin", log.LstdFlags)),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zipkin exporter: %w", err)
+		}
+		return exporter, nil
+	case "otlp":
+		// Endpoint e demais opções vêm das variáveis padrão OTEL_EXPORTER_OTLP_*
+		var (
+			exporter sdktrace.SpanExporter
+			err      error
+		)
+		if cfg.OTLPProtocol == "http/protobuf" {
+			exporter, err = otlptracehttp.New(context.Background())
+		} else {
+			exporter, err = otlptracegrpc.New(context.Background())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create otlp exporter: %w", err)
+		}
+		return exporter, nil
+	case "stdout":
+		exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout exporter: %w", err)
+		}
+		return exporter, nil
+	}
+	return nil, fmt.Errorf("unknown trace exporter %q", name)
 }
 
 func initTracer() (*sdktrace.TracerProvider, error) {
-	// Configura o exporter Zipkin
-	exporter, err := zipkin.New(
-		"http://zipkin:9411/api/v2/spans",
-		zipkin.WithLogger(log.New(os.Stdout, "zipkin", log.LstdFlags)),
-	)
+	profile, err := loadTracingProfile(cfg.DeployEnv, cfg.TracingProfilesFile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create zipkin exporter: %w", err)
+		return nil, err
 	}
+	// OTEL_TRACES_SAMPLER_ARG tem precedência sobre a taxa do perfil
+	if cfg.TracesSamplerArg != "" {
+		ratio, err := strconv.ParseFloat(cfg.TracesSamplerArg, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			return nil, fmt.Errorf("invalid OTEL_TRACES_SAMPLER_ARG %q: must be a ratio between 0 and 1", cfg.TracesSamplerArg)
+		}
+		profile.SamplerRatio = ratio
+	}
+	slog.Info("Using tracing profile", "profile", cfg.DeployEnv, "sampler_ratio", profile.SamplerRatio)
 
-	// Configura o resource com informações do serviço
-	res, err := resource.New(
-		context.Background(),
-		resource.WithAttributes(
-			semconv.ServiceName("service-a"),
-			semconv.ServiceVersion("1.0.0"),
-			attribute.String("environment", "development"),
-		),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create resource: %w", err)
+	var exporters []sdktrace.SpanExporter
+	for _, name := range cfg.TraceExporters {
+		exporter, err := newExporter(name, profile)
+		if err != nil {
+			slog.Warn("Skipping trace exporter", "exporter", name, "error", err)
+			continue
+		}
+		exporters = append(exporters, exporter)
+	}
+	if len(exporters) == 0 {
+		return nil, fmt.Errorf("no trace exporter available from %v", cfg.TraceExporters)
 	}
 
-	// Cria o TracerProvider
-	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(exporter),
-		sdktrace.WithResource(res),
-		sdktrace.WithSampler(sdktrace.AlwaysSample()),
-	)
+	tp, err := newTracerProvider(exporters, profile.SamplerRatio)
+	if err != nil {
+		return nil, err
+	}
 
 	// Configura o propagador para tracing distribuído
 	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
@@ -65,111 +129,638 @@ func initTracer() (*sdktrace.TracerProvider, error) {
 	return tp, nil
 }
 
+// newTracerProvider cria o TracerProvider com o resource do serviço e um batch
+// span processor por exporter: se um destino falhar, os demais continuam
+// recebendo os spans
+func newTracerProvider(exporters []sdktrace.SpanExporter, samplerRatio float64) (*sdktrace.TracerProvider, error) {
+	// Configura o resource com informações do serviço
+	attrs := []attribute.KeyValue{
+		semconv.ServiceName("service-a"),
+		semconv.ServiceVersion("1.0.0"),
+		attribute.String("environment", cfg.DeployEnv),
+	}
+	// Região e zona da implantação, para filtrar traces em ambientes multirregião
+	if cfg.DeployRegion != "" {
+		attrs = append(attrs, semconv.CloudRegion(cfg.DeployRegion))
+	}
+	if cfg.DeployZone != "" {
+		attrs = append(attrs, semconv.CloudAvailabilityZone(cfg.DeployZone))
+	}
+	res, err := resource.New(context.Background(), resource.WithAttributes(attrs...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(samplerRatio))),
+	}
+	for _, exporter := range exporters {
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+	return sdktrace.NewTracerProvider(opts...), nil
+}
+
+// countingReader conta os bytes lidos do corpo da requisição
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// requireCompleteBody rejeita com 400 corpos menores que o Content-Length
+// declarado, quando VALIDATE_CONTENT_LENGTH está ativo. O net/http já limita a
+// leitura ao Content-Length e devolve io.ErrUnexpectedEOF quando a conexão
+// termina antes dele.
+func requireCompleteBody(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.ValidateContentLength || r.ContentLength < 0 {
+			next(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			writeJSONError(w, http.StatusBadRequest, "truncated body", "truncated_body")
+			return
+		}
+		if err != nil {
+			writeBodyError(w, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next(w, r)
+	}
+}
+
+// withRequestTimeout limita cada requisição a REQUEST_TIMEOUT. O prazo vale
+// para todo o contexto da requisição, inclusive as chamadas de saída feitas
+// com ele: quando ele se esgota, a chamada ao Service B é cancelada e a
+// conexão fechada, o que cancela também o contexto da requisição no Service B
+func withRequestTimeout(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), cfg.RequestTimeout)
+		defer cancel()
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// requestTimedOut indica se o prazo de REQUEST_TIMEOUT da requisição se esgotou
+func requestTimedOut(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
+
+func writeRequestTimeout(w http.ResponseWriter) {
+	writeJSONError(w, http.StatusGatewayTimeout, "request timeout", "request_timeout")
+}
+
+// Cliente HTTP compartilhado pelas chamadas externas, para reaproveitar conexões
+var httpClient *http.Client
+
+func newHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = cfg.UpstreamDisableKeepAlive
+	// O timeout limita a chamada inteira; prazos menores no contexto da
+	// requisição continuam valendo
+	return &http.Client{Transport: transport, Timeout: cfg.HTTPClientTimeout}
+}
+
+// setAttributes registra atributos no span truncando valores de texto em
+// TRACE_ATTRIBUTE_MAX_LENGTH caracteres, para que corpos de erro e URLs
+// longas não inflem o armazenamento dos traces
+func setAttributes(span trace.Span, attrs ...attribute.KeyValue) {
+	for i, attr := range attrs {
+		if attr.Value.Type() == attribute.STRING {
+			attrs[i] = attribute.String(string(attr.Key), truncate(attr.Value.AsString(), cfg.TraceAttributeMaxLength))
+		}
+	}
+	span.SetAttributes(attrs...)
+}
+
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max-1]) + "…"
+}
+
+// phaseSpan representa uma fase registrada como eventos no span pai,
+// usada quando TRACE_VERBOSITY=minimal
+type phaseSpan struct {
+	trace.Span
+	name string
+}
+
+func (p phaseSpan) End(...trace.SpanEndOption) {
+	p.Span.AddEvent(p.name + " end")
+}
+
+func startPhase(ctx context.Context, tracer trace.Tracer, name string) (context.Context, trace.Span) {
+	var span trace.Span
+	if cfg.TraceVerbosity != "minimal" {
+		ctx, span = tracer.Start(ctx, name)
+	} else {
+		span = trace.SpanFromContext(ctx)
+		span.AddEvent(name + " start")
+		span = phaseSpan{Span: span, name: name}
+	}
+	if timings := phaseTimingsFrom(ctx); timings != nil {
+		span = timedSpan{Span: span, name: name, start: time.Now(), timings: timings}
+	}
+	return ctx, span
+}
+
+// normalizeCEP remove hífen, ponto e espaços, aceitando formatos como
+// "01310-100" e "01.310 100"; demais caracteres são mantidos para que a
+// validação os rejeite
+func normalizeCEP(cep string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', '.', ' ':
+			return -1
+		}
+		return r
+	}, cep)
+}
+
+// isValidCity aceita nomes de até 100 caracteres compostos de letras,
+// espaços, hífens, apóstrofos e pontos
+func isValidCity(city string) bool {
+	if city == "" || utf8.RuneCountInString(city) > 100 {
+		return false
+	}
+	for _, r := range city {
+		if !unicode.IsLetter(r) && !strings.ContainsRune(" -'.", r) {
+			return false
+		}
+	}
+	return true
+}
+
 func isValidCEP(cep string) bool {
 	if len(cep) != 8 {
 		return false
 	}
-	_, err := strconv.Atoi(cep)
-	return err == nil
+	for _, c := range cep {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
 }
 
-func handleCEP(w http.ResponseWriter, r *http.Request) {
-	tracer := otel.Tracer("service-a")
-	ctx, span := tracer.Start(r.Context(), "handleCEP")
-	defer span.End()
+// decodeCEPRequest lê o CEP do campo configurado em CEP_FIELD_NAME, para que
+// integrações que enviam {"zip": ...} ou {"postal_code": ...} não precisem
+// adaptar o payload. Os nomes dos campos não diferenciam maiúsculas, como na
+// decodificação padrão de JSON. Campos desconhecidos e a falta do CEP (sem
+// cidade, com ACCEPT_CITY) são rejeitados.
+func decodeCEPRequest(r io.Reader) (CEPRequest, error) {
+	var fields map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&fields); err != nil {
+		return CEPRequest{}, err
+	}
+	for name := range fields {
+		if !strings.EqualFold(name, cfg.CEPFieldName) && !(strings.EqualFold(name, "city") && cfg.AcceptCity) {
+			return CEPRequest{}, unknownFieldError(name)
+		}
+	}
+
+	var req CEPRequest
+	if raw, ok := lookupField(fields, cfg.CEPFieldName); ok {
+		if err := json.Unmarshal(raw, &req.CEP); err != nil {
+			return CEPRequest{}, fmt.Errorf("field %q must be a string", cfg.CEPFieldName)
+		}
+	}
+	if raw, ok := lookupField(fields, "city"); ok && cfg.AcceptCity {
+		if err := json.Unmarshal(raw, &req.City); err != nil {
+			return CEPRequest{}, fmt.Errorf("field \"city\" must be a string")
+		}
+	}
+	if strings.TrimSpace(req.CEP) == "" && strings.TrimSpace(req.City) == "" {
+		return CEPRequest{}, errMissingCEP
+	}
+	return req, nil
+}
 
-	span.SetAttributes(
+// lookupField devolve o campo name sem diferenciar maiúsculas; a grafia exata
+// tem precedência
+func lookupField(fields map[string]json.RawMessage, name string) (json.RawMessage, bool) {
+	if raw, ok := fields[name]; ok {
+		return raw, true
+	}
+	for key, raw := range fields {
+		if strings.EqualFold(key, name) {
+			return raw, true
+		}
+	}
+	return nil, false
+}
+
+// isBusinessError indica os status de erros esperados do domínio (CEP inválido
+// ou não encontrado), em oposição a falhas de infraestrutura
+func isBusinessError(status int) bool {
+	return status == http.StatusUnprocessableEntity || status == http.StatusNotFound
+}
+
+// writeBusinessError responde um erro esperado do domínio. Com SOFT_ERRORS=true
+// a resposta tem status 200 e corpo {"error": ...}, para clientes que não
+// tratam bem respostas não-2xx
+func writeBusinessError(w http.ResponseWriter, status int, message, code string) {
+	if cfg.SoftErrors {
+		status = http.StatusOK
+	}
+	writeJSONError(w, status, message, code)
+}
+
+// startRequest abre o span raiz de uma requisição ao /cep com os atributos
+// comuns às rotas POST e GET
+func startRequest(r *http.Request, name string) (context.Context, trace.Span) {
+	ctx, span := otel.Tracer("service-a").Start(withPhaseTimings(r.Context()), name)
+	recordRequestSpan(ctx, span)
+	setAttributes(span,
 		attribute.String("http.method", r.Method),
 		attribute.String("http.path", r.URL.Path),
 	)
+	if id, ok := apiKeyID(ctx); ok {
+		setAttributes(span, attribute.String("auth.api_key_id", id))
+	}
+	return ctx, span
+}
 
-	var req CEPRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+// handleCEP atende POST /cep, com o CEP no corpo JSON
+func handleCEP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	ctx, span := startRequest(r, "handleCEP")
+	defer span.End()
+	defer logSlowRequest(ctx, start)
+
+	reqBody := &countingReader{Reader: r.Body}
+	req, err := decodeCEPRequest(reqBody)
+	setAttributes(span, attribute.Int64("http.request_content_length", reqBody.n))
+	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Invalid request body")
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		writeBodyError(w, err)
 		return
 	}
 
+	resolveCEP(ctx, w, r, req)
+}
+
+// handleCEPPath atende GET /cep/{cep}, para testes rápidos e uso no navegador
+func handleCEPPath(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	ctx, span := startRequest(r, "handleCEPPath")
+	defer span.End()
+	defer logSlowRequest(ctx, start)
+
+	resolveCEP(ctx, w, r, CEPRequest{CEP: r.PathValue("cep")})
+}
+
+// resolveCEP valida a requisição e a repassa ao Service B; é o fluxo comum às
+// rotas POST e GET
+func resolveCEP(ctx context.Context, w http.ResponseWriter, r *http.Request, req CEPRequest) {
+	tracer := otel.Tracer("service-a")
+	span := trace.SpanFromContext(ctx)
+
 	// Validação do CEP
-	ctx, validateSpan := tracer.Start(ctx, "validate-cep")
-	if !isValidCEP(req.CEP) {
+	_, validateSpan := startPhase(ctx, tracer, "validate-cep")
+	req.City = strings.TrimSpace(req.City)
+	if req.City != "" && !isValidCity(req.City) {
+		validateSpan.RecordError(fmt.Errorf("invalid city"))
+		validateSpan.SetStatus(codes.Error, "Invalid city")
+		validateSpan.End()
+		writeBusinessError(w, http.StatusUnprocessableEntity, "invalid city", "invalid_city")
+		return
+	}
+	cep := normalizeCEP(req.CEP)
+	// Com a cidade informada o CEP é opcional, mas se presente deve ser válido
+	if (req.City == "" || cep != "") && !isValidCEP(cep) {
 		validateSpan.RecordError(fmt.Errorf("invalid zipcode"))
 		validateSpan.SetStatus(codes.Error, "Invalid zipcode")
 		validateSpan.End()
-		http.Error(w, "invalid zipcode", http.StatusUnprocessableEntity)
+		writeBusinessError(w, http.StatusUnprocessableEntity, "invalid zipcode", "invalid_zipcode")
+		return
+	}
+	if _, err := parseUnits(r.URL.Query().Get("units")); err != nil {
+		validateSpan.RecordError(err)
+		validateSpan.SetStatus(codes.Error, "Invalid units parameter")
+		validateSpan.End()
+		writeJSONError(w, http.StatusBadRequest, err.Error(), "invalid_units")
 		return
 	}
 	validateSpan.End()
+	if cep != req.CEP {
+		req.RequestedCEP = req.CEP
+	}
+	req.CEP = cep
+	if cep != "" {
+		ctx = withCEP(ctx, cep)
+	}
+	if req.City != "" {
+		ctx = withCity(ctx, req.City)
+	}
 
 	// Chamada ao Service B
-	serviceBURL := "http://service-b:8081/temperature"
-	reqBody, err := json.Marshal(req)
+	payload, err := json.Marshal(req)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to marshal request")
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, "internal server error", "internal_error")
 		return
 	}
 
-	ctx, callSpan := tracer.Start(ctx, "call-service-b")
+	resp, err := callServiceB(ctx, serviceBURL(cfg.ServiceBURL, r), forwardedHeaders(r), payload)
+	if err != nil {
+		writeServiceBError(ctx, w, err)
+		return
+	}
+
+	if isBusinessError(resp.status) {
+		var errResp ErrorResponse
+		if err := json.Unmarshal(resp.body, &errResp); err != nil {
+			errResp.Error = strings.TrimSpace(string(resp.body))
+		}
+		writeBusinessError(w, resp.status, errResp.Error, errResp.Code)
+		return
+	}
+
+	writeServiceBResponse(ctx, w, resp)
+}
+
+type CEPBatchRequest struct {
+	CEPs []string `json:"ceps"`
+}
+
+// handleCEPBatch atende POST /cep/batch: normaliza os CEPs e repassa o lote ao
+// Service B, que valida e resolve cada item e devolve os resultados na ordem
+// recebida
+func handleCEPBatch(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	ctx, span := startRequest(r, "handleCEPBatch")
+	defer span.End()
+	defer logSlowRequest(ctx, start)
+
+	var req CEPBatchRequest
+	reqBody := &countingReader{Reader: r.Body}
+	err := json.NewDecoder(reqBody).Decode(&req)
+	setAttributes(span, attribute.Int64("http.request_content_length", reqBody.n))
+	if err != nil {
+		span.SetStatus(codes.Error, "Invalid request body")
+		writeBodyError(w, err)
+		return
+	}
+	if len(req.CEPs) == 0 {
+		span.SetStatus(codes.Error, "Invalid request body")
+		writeJSONError(w, http.StatusBadRequest, "invalid request body", "invalid_request_body")
+		return
+	}
+	setAttributes(span, attribute.Int("batch.size", len(req.CEPs)))
+	if _, err := parseUnits(r.URL.Query().Get("units")); err != nil {
+		span.SetStatus(codes.Error, "Invalid units parameter")
+		writeJSONError(w, http.StatusBadRequest, err.Error(), "invalid_units")
+		return
+	}
+
+	for i, cep := range req.CEPs {
+		req.CEPs[i] = normalizeCEP(cep)
+	}
+	payload, err := json.Marshal(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to marshal request")
+		writeJSONError(w, http.StatusInternalServerError, "internal server error", "internal_error")
+		return
+	}
+
+	resp, err := callServiceB(ctx, serviceBURL(cfg.ServiceBURL+"/batch", r), nil, payload)
+	if err != nil {
+		writeServiceBError(ctx, w, err)
+		return
+	}
+	writeServiceBResponse(ctx, w, resp)
+}
+
+// errServiceBCall indica que o Service B não pôde ser alcançado
+var errServiceBCall = errors.New("failed to call service b")
+
+// serviceBURL monta o endereço da chamada ao Service B, repassando os
+// parâmetros de consulta da requisição (ex.: ?minimal=true)
+func serviceBURL(base string, r *http.Request) string {
+	if r.URL.RawQuery != "" {
+		return base + "?" + r.URL.RawQuery
+	}
+	return base
+}
+
+// forwardedHeaders seleciona os cabeçalhos do cliente repassados ao Service B:
+// o Accept (ex.: application/geo+json) e o If-None-Match, para respostas 304
+func forwardedHeaders(r *http.Request) http.Header {
+	header := make(http.Header)
+	for _, name := range []string{"Accept", "If-None-Match"} {
+		if value := r.Header.Get(name); value != "" {
+			header.Set(name, value)
+		}
+	}
+	return header
+}
+
+// serviceBResponse guarda a resposta do Service B para ser repassada ao cliente
+type serviceBResponse struct {
+	status      int
+	contentType string
+	etag        string
+	body        []byte
+}
+
+// callServiceB faz o POST de payload ao Service B, propagando o contexto de
+// tracing e os cabeçalhos informados, e devolve a resposta
+func callServiceB(ctx context.Context, url string, header http.Header, payload []byte) (serviceBResponse, error) {
+	tracer := otel.Tracer("service-a")
+	ctx, callSpan := startPhase(ctx, tracer, "call-service-b")
 	defer callSpan.End()
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", serviceBURL, bytes.NewBuffer(reqBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(payload))
 	if err != nil {
 		callSpan.RecordError(err)
 		callSpan.SetStatus(codes.Error, "Failed to create request")
-		http.Error(w, "internal server error", http.StatusInternalServerError)
-		return
+		return serviceBResponse{}, err
 	}
 
 	// Propagação do contexto para tracing distribuído
 	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(httpReq.Header))
 	httpReq.Header.Set("Content-Type", "application/json")
+	for name, values := range header {
+		httpReq.Header[name] = values
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(httpReq)
+	resp, err := httpClient.Do(httpReq)
 	if err != nil {
 		callSpan.RecordError(err)
 		callSpan.SetStatus(codes.Error, "Failed to call service")
-		http.Error(w, "failed to call service b", http.StatusInternalServerError)
-		return
+		return serviceBResponse{}, fmt.Errorf("%w: %v", errServiceBCall, err)
 	}
 	defer resp.Body.Close()
 
-	callSpan.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+	setAttributes(callSpan, attribute.Int("http.status_code", resp.StatusCode))
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		callSpan.RecordError(err)
 		callSpan.SetStatus(codes.Error, "Failed to read response")
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return serviceBResponse{}, err
+	}
+	return serviceBResponse{
+		status:      resp.StatusCode,
+		contentType: resp.Header.Get("Content-Type"),
+		etag:        resp.Header.Get("ETag"),
+		body:        body,
+	}, nil
+}
+
+func writeServiceBError(ctx context.Context, w http.ResponseWriter, err error) {
+	if requestTimedOut(ctx) {
+		writeRequestTimeout(w)
+		return
+	}
+	if errors.Is(err, errServiceBCall) {
+		writeJSONError(w, http.StatusInternalServerError, "failed to call service b", "service_b_unavailable")
 		return
 	}
+	writeJSONError(w, http.StatusInternalServerError, "internal server error", "internal_error")
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(resp.StatusCode)
-	if _, err := w.Write(body); err != nil {
+// writeServiceBResponse repassa ao cliente a resposta do Service B
+func writeServiceBResponse(ctx context.Context, w http.ResponseWriter, resp serviceBResponse) {
+	span := trace.SpanFromContext(ctx)
+	if resp.etag != "" {
+		w.Header().Set("ETag", resp.etag)
+		w.Header().Set("Vary", "Accept")
+	}
+	if resp.status == http.StatusNotModified {
+		w.WriteHeader(resp.status)
+		return
+	}
+	contentType := resp.contentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(resp.status)
+	n, err := w.Write(resp.body)
+	if err != nil {
 		span.RecordError(err)
 	}
+	setAttributes(span, attribute.Int("http.response_content_length", n))
+}
+
+type HealthResponse struct {
+	SchemaVersion string `json:"schema_version"`
+	Status        string `json:"status"`
+}
+
+// handleHealth responde às sondas de saúde com o status de HEALTH_STATUS_CODE;
+// com 204 a resposta não tem corpo
+func handleHealth(w http.ResponseWriter, r *http.Request) {
+	if cfg.HealthStatusCode == http.StatusNoContent {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, cfg.HealthStatusCode, HealthResponse{SchemaVersion: schemaVersion, Status: "ok"})
+}
+
+// registerRoutes registra as rotas da API em mux
+func registerRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /cep", instrument("/cep", rateLimit(withRequestTimeout(limitBody(requireCompleteBody(requireAPIKey(requireHMAC(handleCEP))))))))
+	mux.HandleFunc("/cep", instrument("/cep", methodNotAllowed(http.MethodPost)))
+	mux.HandleFunc("GET /cep/{cep}", instrument("/cep/{cep}", rateLimit(withRequestTimeout(requireAPIKey(requireHMAC(handleCEPPath))))))
+	mux.HandleFunc("POST /cep/batch", instrument("/cep/batch", rateLimit(withRequestTimeout(limitBody(requireCompleteBody(requireAPIKey(requireHMAC(handleCEPBatch))))))))
+	// Sem método, /cep/batch conflitaria com GET /cep/{cep} no mux; cada
+	// método é registrado para responder o 405 em JSON, e não como CEP inválido
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions} {
+		mux.HandleFunc(method+" /cep/batch", instrument("/cep/batch", methodNotAllowed(http.MethodPost)))
+	}
+	mux.HandleFunc("/health", instrument("/health", handleHealth))
+	if cfg.OpenAPIEnabled {
+		mux.HandleFunc("GET /openapi.json", handleOpenAPI)
+	}
+	if cfg.ProbeEnabled {
+		// A sonda dispara consultas reais: mesma proteção de GET /cep/{cep}
+		mux.HandleFunc("GET /probe", instrument("/probe", rateLimit(withRequestTimeout(requireAPIKey(requireHMAC(handleProbe))))))
+	}
 }
 
 func main() {
+	var err error
+	cfg, err = loadConfig()
+	if err != nil {
+		fatal("Invalid configuration", "error", err)
+	}
+	if err := initLogger(cfg.LogLevel); err != nil {
+		fatal("Invalid LOG_LEVEL", "error", err)
+	}
+	slog.Info("Forwarding requests to Service B", "url", cfg.ServiceBURL)
+	httpClient = newHTTPClient()
+	apiKeys, authConfigErr = parseAPIKeys(cfg.APIKeys)
+	if authConfigErr != nil {
+		slog.Error("Failed to load API keys", "fail_mode", cfg.AuthFailMode, "error", authConfigErr)
+	} else if len(apiKeys) > 0 {
+		slog.Info("API key authentication enabled", "keys", len(apiKeys))
+	}
+	if cfg.RateLimitRPS > 0 {
+		limiter = newRateLimiter(cfg.RateLimitRPS, cfg.RateLimitBurst)
+		slog.Info("Rate limiting enabled", "rps", cfg.RateLimitRPS, "burst", cfg.RateLimitBurst)
+	}
+	if cfg.UpstreamDisableKeepAlive {
+		slog.Info("Keep-alive disabled for upstream connections")
+	}
+
 	// Inicializa o tracer
 	tp, err := initTracer()
 	if err != nil {
-		log.Fatalf("Failed to initialize tracer: %v", err)
+		fatal("Failed to initialize tracer", "error", err)
 	}
-	defer func() {
-		if err := tp.Shutdown(context.Background()); err != nil {
-			log.Printf("Failed to shutdown tracer: %v", err)
-		}
-	}()
 
 	// Configura o servidor HTTP
-	http.HandleFunc("/cep", handleCEP)
-	log.Println("Service A listening on :8080")
-	if err := http.ListenAndServe(":8080", nil); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	registerRoutes(http.DefaultServeMux)
+	serveMetrics()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: ":" + cfg.Port, Handler: logRequests(http.DefaultServeMux)}
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("Service A listening", "port", cfg.Port)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		fatal("Failed to start server", "error", err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	// Encerramento: para de aceitar conexões, aguarda as requisições em
+	// andamento por até SHUTDOWN_GRACE_PERIOD e só então descarrega os spans
+	slog.Info("Shutting down, draining connections", "grace_period", cfg.ShutdownGracePeriod.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownGracePeriod)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Failed to drain connections", "error", err)
+	} else {
+		slog.Info("Server drained")
+	}
+	if err := tp.Shutdown(context.Background()); err != nil {
+		slog.Error("Failed to shutdown tracer", "error", err)
+	} else {
+		slog.Info("Tracer flushed")
 	}
+	slog.Info("Service A stopped")
 }
@@ -0,0 +1,610 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// setupTest carrega a configuração com as variáveis informadas (pares nome,
+// valor) e recria o estado global usado pelos handlers, como faz main
+func setupTest(t *testing.T, env ...string) {
+	t.Helper()
+	for i := 0; i+1 < len(env); i += 2 {
+		t.Setenv(env[i], env[i+1])
+	}
+
+	c, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	cfg = c
+
+	httpClient = newHTTPClient()
+	apiKeys, authConfigErr = parseAPIKeys(cfg.APIKeys)
+	seenSignaturesMu.Lock()
+	seenSignatures = make(map[string]time.Time)
+	seenSignaturesMu.Unlock()
+	limiter = nil
+	if cfg.RateLimitRPS > 0 {
+		limiter = newRateLimiter(cfg.RateLimitRPS, cfg.RateLimitBurst)
+	}
+}
+
+// serviceBStub é um Service B falso: responde sempre status e body e guarda
+// as requisições recebidas
+type serviceBStub struct {
+	*httptest.Server
+	status int
+	body   string
+
+	mu       sync.Mutex
+	received []serviceBRequest
+}
+
+type serviceBRequest struct {
+	path   string
+	query  string
+	header http.Header
+	body   string
+}
+
+func newServiceBStub(t *testing.T, status int, body string) *serviceBStub {
+	t.Helper()
+	stub := &serviceBStub{status: status, body: body}
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		stub.mu.Lock()
+		stub.received = append(stub.received, serviceBRequest{r.URL.Path, r.URL.RawQuery, r.Header.Clone(), string(payload)})
+		stub.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(stub.status)
+		io.WriteString(w, stub.body)
+	}))
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+func (s *serviceBStub) requests() []serviceBRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]serviceBRequest(nil), s.received...)
+}
+
+const serviceBTemperature = `{"schema_version":"1.1","city":"São Paulo","temp_C":25,"temp_F":77,"temp_K":298.2}`
+
+// setupWithServiceB é setupTest com SERVICE_B_URL apontando para um Service B
+// falso
+func setupWithServiceB(t *testing.T, status int, body string, env ...string) *serviceBStub {
+	t.Helper()
+	stub := newServiceBStub(t, status, body)
+	setupTest(t, append([]string{"SERVICE_B_URL", stub.URL + "/temperature"}, env...)...)
+	return stub
+}
+
+// recordSpans instala um TracerProvider global que guarda em memória os spans
+// encerrados durante o teste
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+// serve executa a requisição nas rotas registradas por main
+func serve(r *http.Request) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	registerRoutes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, r)
+	return rec
+}
+
+func postCEP(t *testing.T, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/cep", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	return serve(r)
+}
+
+func decodeBody[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("invalid JSON body %q: %v", rec.Body.String(), err)
+	}
+	return v
+}
+
+func assertError(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("status = %d, want %d (body %s)", rec.Code, status, rec.Body.String())
+	}
+	if body := decodeBody[ErrorResponse](t, rec); body.Code != code {
+		t.Errorf("code = %q, want %q", body.Code, code)
+	}
+}
+
+func TestCEPForwardsToServiceB(t *testing.T) {
+	stub := setupWithServiceB(t, http.StatusOK, serviceBTemperature)
+
+	rec := postCEP(t, `{"cep": "01001000"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+	}
+	if rec.Body.String() != serviceBTemperature {
+		t.Errorf("body = %s, want the Service B response", rec.Body.String())
+	}
+	reqs := stub.requests()
+	if len(reqs) != 1 || reqs[0].path != "/temperature" {
+		t.Fatalf("Service B received %+v, want one call to /temperature", reqs)
+	}
+	if reqs[0].header.Get("Content-Type") != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", reqs[0].header.Get("Content-Type"))
+	}
+}
+
+func TestCEPInvalidIsNotForwarded(t *testing.T) {
+	stub := setupWithServiceB(t, http.StatusOK, serviceBTemperature)
+
+	rec := postCEP(t, `{"cep": "0100100"}`)
+	assertError(t, rec, http.StatusUnprocessableEntity, "invalid_zipcode")
+	if n := len(stub.requests()); n != 0 {
+		t.Errorf("Service B received %d requests, want 0", n)
+	}
+}
+
+func TestCEPServiceBUnavailable(t *testing.T) {
+	stub := newServiceBStub(t, http.StatusOK, "")
+	stub.Close()
+	setupTest(t, "SERVICE_B_URL", stub.URL+"/temperature")
+
+	rec := postCEP(t, `{"cep": "01001000"}`)
+	assertError(t, rec, http.StatusInternalServerError, "service_b_unavailable")
+}
+
+func TestCEPForwardsNormalizedCEP(t *testing.T) {
+	for _, tc := range []struct {
+		body, want string
+	}{
+		// O CEP informado segue à parte, para o requested_cep do Service B
+		{`{"cep": "01001-000"}`, `{"cep":"01001000","requested_cep":"01001-000"}`},
+		{`{"cep": "01001000"}`, `{"cep":"01001000"}`},
+	} {
+		stub := setupWithServiceB(t, http.StatusOK, serviceBTemperature)
+
+		if rec := postCEP(t, tc.body); rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want 200 (body %s)", tc.body, rec.Code, rec.Body.String())
+		}
+		reqs := stub.requests()
+		if len(reqs) != 1 {
+			t.Fatalf("%s: Service B received %d requests, want 1", tc.body, len(reqs))
+		}
+		if reqs[0].body != tc.want {
+			t.Errorf("%s: forwarded body = %s, want %s", tc.body, reqs[0].body, tc.want)
+		}
+	}
+}
+
+func TestCEPBatchForwardsNormalizedCEPs(t *testing.T) {
+	const batchResponse = `{"schema_version":"1.1","results":[]}`
+	stub := setupWithServiceB(t, http.StatusOK, batchResponse)
+
+	r := httptest.NewRequest(http.MethodPost, "/cep/batch?units=c", strings.NewReader(`{"ceps": ["01001-000", " 01002000 ", "123"]}`))
+	r.Header.Set("Content-Type", "application/json")
+	rec := serve(r)
+	if rec.Code != http.StatusOK || rec.Body.String() != batchResponse {
+		t.Fatalf("status = %d, body %s, want 200 with Service B's body", rec.Code, rec.Body.String())
+	}
+
+	reqs := stub.requests()
+	if len(reqs) != 1 {
+		t.Fatalf("Service B received %d requests, want 1", len(reqs))
+	}
+	// CEPs inválidos seguem como estão, para o Service B respondê-los no item
+	if want := `{"ceps":["01001000","01002000","123"]}`; reqs[0].body != want {
+		t.Errorf("forwarded body = %s, want %s", reqs[0].body, want)
+	}
+	if reqs[0].path != "/temperature/batch" || reqs[0].query != "units=c" {
+		t.Errorf("forwarded to %s?%s, want /temperature/batch?units=c", reqs[0].path, reqs[0].query)
+	}
+}
+
+func TestHTTPClientKeepAlive(t *testing.T) {
+	for _, tc := range []struct {
+		env  string
+		want bool
+	}{
+		{"", false},
+		{"false", false},
+		{"true", true},
+	} {
+		t.Run("UPSTREAM_DISABLE_KEEPALIVE="+tc.env, func(t *testing.T) {
+			setupTest(t, "UPSTREAM_DISABLE_KEEPALIVE", tc.env)
+			transport, ok := httpClient.Transport.(*http.Transport)
+			if !ok {
+				t.Fatalf("transport = %T, want *http.Transport", httpClient.Transport)
+			}
+			if transport.DisableKeepAlives != tc.want {
+				t.Errorf("DisableKeepAlives = %v, want %v", transport.DisableKeepAlives, tc.want)
+			}
+		})
+	}
+}
+
+func TestCEPErrorModes(t *testing.T) {
+	const notFound = `{"schema_version":"1.1","error":"can not find zipcode","code":"zipcode_not_found"}`
+	const upstreamFailure = `{"schema_version":"1.1","error":"failed to fetch temperature","code":"weather_fetch_failed"}`
+	tests := []struct {
+		name         string
+		cep          string
+		serviceB     int
+		serviceBBody string
+		strict, soft int
+		code         string
+	}{
+		{"invalid zipcode", "123", http.StatusOK, serviceBTemperature, http.StatusUnprocessableEntity, http.StatusOK, "invalid_zipcode"},
+		{"zipcode not found", "99999999", http.StatusNotFound, notFound, http.StatusNotFound, http.StatusOK, "zipcode_not_found"},
+		// Falhas de infraestrutura continuam 5xx mesmo com SOFT_ERRORS
+		{"upstream failure", "01001000", http.StatusInternalServerError, upstreamFailure, http.StatusInternalServerError, http.StatusInternalServerError, "weather_fetch_failed"},
+	}
+	for _, tt := range tests {
+		for _, mode := range []struct {
+			softErrors string
+			want       int
+		}{
+			{"false", tt.strict},
+			{"true", tt.soft},
+		} {
+			t.Run(tt.name+"/SOFT_ERRORS="+mode.softErrors, func(t *testing.T) {
+				setupWithServiceB(t, tt.serviceB, tt.serviceBBody, "SOFT_ERRORS", mode.softErrors)
+
+				assertError(t, postCEP(t, `{"cep": "`+tt.cep+`"}`), mode.want, tt.code)
+			})
+		}
+	}
+}
+
+func TestCEPServiceBUnavailableIsNotSoftened(t *testing.T) {
+	stub := newServiceBStub(t, http.StatusOK, "")
+	stub.Close()
+	setupTest(t, "SERVICE_B_URL", stub.URL+"/temperature", "SOFT_ERRORS", "true")
+
+	assertError(t, postCEP(t, `{"cep": "01001000"}`), http.StatusInternalServerError, "service_b_unavailable")
+}
+
+func TestCEPFieldName(t *testing.T) {
+	tests := []struct {
+		name      string
+		fieldName string
+		body      string
+		wantCode  string // vazio quando o CEP deve ser repassado
+	}{
+		{"default", "", `{"cep": "01001000"}`, ""},
+		{"default rejects alternate", "", `{"postal_code": "01001000"}`, "unknown_field"},
+		{"postal_code", "postal_code", `{"postal_code": "01001000"}`, ""},
+		{"zip", "zip", `{"zip": "01001000"}`, ""},
+		{"configured rejects cep", "zip", `{"cep": "01001000"}`, "unknown_field"},
+		{"configured missing", "zip", `{}`, "missing_cep"},
+		{"default case-insensitive", "", `{"CEP": "01001000"}`, ""},
+		{"configured case-insensitive", "postal_code", `{"Postal_Code": "01001000"}`, ""},
+		{"exact case takes precedence", "", `{"CEP": "99999999", "cep": "01001000"}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := setupWithServiceB(t, http.StatusOK, serviceBTemperature, "CEP_FIELD_NAME", tt.fieldName)
+
+			rec := postCEP(t, tt.body)
+			if tt.wantCode != "" {
+				assertError(t, rec, http.StatusBadRequest, tt.wantCode)
+				return
+			}
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+			}
+			// O Service B recebe sempre o campo cep
+			reqs := stub.requests()
+			if len(reqs) != 1 || !strings.Contains(reqs[0].body, `"cep":"01001000"`) {
+				t.Errorf("Service B received %+v, want the CEP in the cep field", reqs)
+			}
+		})
+	}
+}
+
+func TestCEPNormalization(t *testing.T) {
+	tests := []struct {
+		name, in, normalized string
+		valid                bool
+	}{
+		{"digits", "01310100", "01310100", true},
+		{"hyphenated", "01310-100", "01310100", true},
+		{"spaced", "01310 100", "01310100", true},
+		{"dotted", "01.310-100", "01310100", true},
+		{"surrounding spaces", " 01310100 ", "01310100", true},
+		{"too short", "0131010", "0131010", false},
+		{"too short hyphenated", "01310-10", "0131010", false},
+		{"too long", "013101000", "013101000", false},
+		{"letters", "0131A100", "0131A100", false},
+		{"slash", "01310/100", "01310/100", false},
+		{"full-width digits", "０１３１０１００", "０１３１０１００", false},
+		{"empty", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := normalizeCEP(tt.in)
+			if got != tt.normalized {
+				t.Errorf("normalizeCEP(%q) = %q, want %q", tt.in, got, tt.normalized)
+			}
+			if isValidCEP(got) != tt.valid {
+				t.Errorf("isValidCEP(%q) = %v, want %v", got, !tt.valid, tt.valid)
+			}
+		})
+	}
+}
+
+func TestCEPAcceptsFormattedInput(t *testing.T) {
+	for _, cep := range []string{"01310-100", "01310 100", "01.310-100"} {
+		setupWithServiceB(t, http.StatusOK, serviceBTemperature)
+		if rec := postCEP(t, `{"cep": "`+cep+`"}`); rec.Code != http.StatusOK {
+			t.Errorf("cep %q: status = %d, want 200 (body %s)", cep, rec.Code, rec.Body.String())
+		}
+	}
+	for _, cep := range []string{"01310-10", "0131A100"} {
+		setupWithServiceB(t, http.StatusOK, serviceBTemperature)
+		assertError(t, postCEP(t, `{"cep": "`+cep+`"}`), http.StatusUnprocessableEntity, "invalid_zipcode")
+	}
+}
+
+func TestCallServiceBTimesOut(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Ler o corpo permite ao servidor perceber a desconexão do cliente
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(slow.Close)
+	setupTest(t, "SERVICE_B_URL", slow.URL+"/temperature", "HTTP_CLIENT_TIMEOUT", "20ms")
+
+	start := time.Now()
+	_, err := callServiceB(context.Background(), cfg.ServiceBURL, nil, []byte(`{"cep":"01001000"}`))
+	if !errors.Is(err, errServiceBCall) || !strings.Contains(err.Error(), "Client.Timeout exceeded") {
+		t.Fatalf("callServiceB error = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("callServiceB took %s, want it cut at HTTP_CLIENT_TIMEOUT", elapsed)
+	}
+
+	rec := postCEP(t, `{"cep": "01001000"}`)
+	assertError(t, rec, http.StatusInternalServerError, "service_b_unavailable")
+}
+
+func TestCEPAcceptCity(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantForward string
+	}{
+		{"city provided", `{"city": " Campinas "}`, `{"cep":"","city":"Campinas"}`},
+		{"city and CEP", `{"cep": "13010-000", "city": "Campinas"}`, `{"cep":"13010000","requested_cep":"13010-000","city":"Campinas"}`},
+		{"CEP only", `{"cep": "01001000"}`, `{"cep":"01001000"}`},
+		{"field names in any case", `{"CEP": "01001000", "City": "São Paulo"}`, `{"cep":"01001000","city":"São Paulo"}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := setupWithServiceB(t, http.StatusOK, serviceBTemperature, "ACCEPT_CITY", "true")
+
+			if rec := postCEP(t, tc.body); rec.Code != http.StatusOK {
+				t.Fatalf("status = %d (body %s)", rec.Code, rec.Body.String())
+			}
+			reqs := stub.requests()
+			if len(reqs) != 1 {
+				t.Fatalf("Service B received %d requests, want 1", len(reqs))
+			}
+			if reqs[0].body != tc.wantForward {
+				t.Errorf("forwarded body = %s, want %s", reqs[0].body, tc.wantForward)
+			}
+		})
+	}
+}
+
+func TestCEPAcceptCityRejects(t *testing.T) {
+	tests := []struct {
+		name       string
+		acceptCity string
+		body       string
+		status     int
+		code       string
+	}{
+		{"invalid city", "true", `{"city": "Campinas 2"}`, http.StatusUnprocessableEntity, "invalid_city"},
+		{"invalid CEP with city", "true", `{"cep": "123", "city": "Campinas"}`, http.StatusUnprocessableEntity, "invalid_zipcode"},
+		{"city not a string", "true", `{"city": 42}`, http.StatusBadRequest, "invalid_request_body"},
+		{"empty city and CEP", "true", `{"city": " "}`, http.StatusBadRequest, "missing_cep"},
+		{"city when disabled", "false", `{"city": "Campinas"}`, http.StatusBadRequest, "unknown_field"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := setupWithServiceB(t, http.StatusOK, serviceBTemperature, "ACCEPT_CITY", tc.acceptCity)
+
+			assertError(t, postCEP(t, tc.body), tc.status, tc.code)
+			if n := len(stub.requests()); n != 0 {
+				t.Errorf("Service B received %d requests, want 0", n)
+			}
+		})
+	}
+}
+
+// postRaw envia POST path ao servidor por uma conexão TCP crua, declarando
+// contentLength bytes mas enviando apenas body, e encerra a escrita para que
+// o servidor perceba o fim do corpo
+func postRaw(t *testing.T, srv *httptest.Server, path string, contentLength int, body string) *http.Response {
+	t.Helper()
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	fmt.Fprintf(conn, "POST %s HTTP/1.1\r\nHost: test\r\nContent-Type: application/json\r\nContent-Length: %d\r\n\r\n%s",
+		path, contentLength, body)
+	if err := conn.(*net.TCPConn).CloseWrite(); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("reading response: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestTruncatedBodyRejected(t *testing.T) {
+	const body = `{"cep": "01001000"}`
+	tests := []struct {
+		name          string
+		validate      string
+		contentLength int
+		wantStatus    int
+		wantCode      string
+	}{
+		{"truncated", "true", len(body) + 11, http.StatusBadRequest, "truncated_body"},
+		{"complete", "true", len(body), http.StatusOK, ""},
+		// Sem a validação, o JSON completo nos bytes recebidos é aceito
+		{"validation disabled", "false", len(body) + 11, http.StatusOK, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := setupWithServiceB(t, http.StatusOK, serviceBTemperature, "VALIDATE_CONTENT_LENGTH", tc.validate)
+			mux := http.NewServeMux()
+			registerRoutes(mux)
+			srv := httptest.NewServer(mux)
+			t.Cleanup(srv.Close)
+
+			var resp *http.Response
+			if tc.contentLength == len(body) {
+				var err error
+				if resp, err = http.Post(srv.URL+"/cep", "application/json", strings.NewReader(body)); err != nil {
+					t.Fatal(err)
+				}
+				defer resp.Body.Close()
+			} else {
+				resp = postRaw(t, srv, "/cep", tc.contentLength, body)
+			}
+			if resp.StatusCode != tc.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tc.wantStatus)
+			}
+			if tc.wantCode == "" {
+				return
+			}
+			var errResp ErrorResponse
+			if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil || errResp.Code != tc.wantCode {
+				t.Errorf("code = %q (%v), want %s", errResp.Code, err, tc.wantCode)
+			}
+			if n := len(stub.requests()); n != 0 {
+				t.Errorf("Service B received %d requests, want 0 for a truncated body", n)
+			}
+		})
+	}
+}
+
+func TestCEPRequestTimeout(t *testing.T) {
+	// O Service B só responde depois que o cliente desiste
+	serviceB := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	t.Cleanup(serviceB.Close)
+	setupTest(t, "SERVICE_B_URL", serviceB.URL+"/temperature", "REQUEST_TIMEOUT", "50ms")
+
+	assertError(t, postCEP(t, `{"cep": "01001000"}`), http.StatusGatewayTimeout, "request_timeout")
+}
+
+func TestCEPMethodNotAllowed(t *testing.T) {
+	stub := setupWithServiceB(t, http.StatusOK, serviceBTemperature)
+
+	for _, path := range []string{"/cep", "/cep/batch"} {
+		for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+			rec := serve(httptest.NewRequest(method, path, nil))
+			assertError(t, rec, http.StatusMethodNotAllowed, "method_not_allowed")
+			if got := rec.Header().Get("Allow"); got != http.MethodPost {
+				t.Errorf("%s %s: Allow = %q, want POST", method, path, got)
+			}
+		}
+	}
+	if n := len(stub.requests()); n != 0 {
+		t.Errorf("Service B received %d requests, want 0", n)
+	}
+}
+
+func TestCEPBodyTooLarge(t *testing.T) {
+	stub := setupWithServiceB(t, http.StatusOK, serviceBTemperature, "MAX_BODY_BYTES", "64")
+	oversized := `{"cep": "` + strings.Repeat("0", 100) + `"}`
+
+	for _, tc := range []struct {
+		name          string
+		contentLength int64
+	}{
+		{"declared length", int64(len(oversized))},
+		// Sem Content-Length o limite só é percebido durante a leitura
+		{"unknown length", -1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/cep", strings.NewReader(oversized))
+			r.Header.Set("Content-Type", "application/json")
+			r.ContentLength = tc.contentLength
+			assertError(t, serve(r), http.StatusRequestEntityTooLarge, "request_body_too_large")
+		})
+	}
+	if n := len(stub.requests()); n != 0 {
+		t.Errorf("Service B received %d requests, want 0 for oversized bodies", n)
+	}
+
+	if rec := postCEP(t, `{"cep": "01001000"}`); rec.Code != http.StatusOK {
+		t.Errorf("body within the limit: status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCEPRequestPayloadValidation(t *testing.T) {
+	tests := []struct {
+		name, body, code string
+	}{
+		{"empty cep", `{"cep": ""}`, "missing_cep"},
+		{"null cep", `{"cep": null}`, "missing_cep"},
+		{"missing cep", `{}`, "missing_cep"},
+		{"extra field", `{"cep": "01001000", "country": "BR"}`, "unknown_field"},
+		{"cep not a string", `{"cep": 1001000}`, "invalid_request_body"},
+		{"not JSON", `cep=01001000`, "invalid_request_body"},
+		{"empty body", "", "invalid_request_body"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := setupWithServiceB(t, http.StatusOK, serviceBTemperature)
+
+			assertError(t, postCEP(t, tc.body), http.StatusBadRequest, tc.code)
+			if n := len(stub.requests()); n != 0 {
+				t.Errorf("Service B received %d requests, want 0", n)
+			}
+		})
+	}
+}
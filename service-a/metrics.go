@@ -0,0 +1,75 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Requisições HTTP recebidas, por rota e status.",
+	}, []string{"route", "code"})
+
+	httpRequestsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "Requisições HTTP em andamento.",
+	})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Duração das requisições HTTP, por rota e status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "code"})
+)
+
+// statusRecorder guarda o status escrito pelo handler para as métricas
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// instrument registra contagem, requisições em andamento e latência do handler
+func instrument(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		httpRequestsInFlight.Inc()
+		defer httpRequestsInFlight.Dec()
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+
+		code := strconv.Itoa(rec.status)
+		httpRequestsTotal.WithLabelValues(route, code).Inc()
+		httpRequestDuration.WithLabelValues(route, code).Observe(time.Since(start).Seconds())
+	}
+}
+
+// serveMetrics publica /metrics em METRICS_ADDR ou, se vazio, no servidor
+// principal
+func serveMetrics() {
+	if cfg.MetricsAddr == "" {
+		http.Handle("/metrics", promhttp.Handler())
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	go func() {
+		slog.Info("Metrics listening", "addr", cfg.MetricsAddr)
+		if err := http.ListenAndServe(cfg.MetricsAddr, mux); err != nil {
+			fatal("Failed to start metrics server", "error", err)
+		}
+	}()
+}
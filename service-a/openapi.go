@@ -0,0 +1,18 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// Especificação OpenAPI das rotas do Service A, mantida junto com os handlers
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// handleOpenAPI serve a especificação em GET /openapi.json, quando
+// OPENAPI_ENABLED está ativo
+func handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
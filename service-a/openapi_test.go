@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPISpec(t *testing.T) {
+	setupTest(t, "OPENAPI_ENABLED", "true")
+
+	rec := serve(httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+
+	var spec struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("spec is not valid JSON: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("openapi = %q, want a 3.x version", spec.OpenAPI)
+	}
+	if _, ok := spec.Paths["/cep"]["post"]; !ok {
+		t.Errorf("spec paths %v lack POST /cep", spec.Paths)
+	}
+	for _, path := range []string{"/cep/{cep}", "/cep/batch", "/health"} {
+		if _, ok := spec.Paths[path]; !ok {
+			t.Errorf("spec lacks %s", path)
+		}
+	}
+}
+
+func TestOpenAPIDisabled(t *testing.T) {
+	setupTest(t)
+
+	if rec := serve(httptest.NewRequest(http.MethodGet, "/openapi.json", nil)); rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404 with OPENAPI_ENABLED off", rec.Code)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// probeRecorder guarda o status e o corpo da resposta da consulta de teste
+type probeRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (p *probeRecorder) Header() http.Header {
+	if p.header == nil {
+		p.header = make(http.Header)
+	}
+	return p.header
+}
+
+func (p *probeRecorder) WriteHeader(status int) {
+	if p.status == 0 {
+		p.status = status
+	}
+}
+
+func (p *probeRecorder) Write(b []byte) (int, error) {
+	p.WriteHeader(http.StatusOK)
+	return p.body.Write(b)
+}
+
+// succeeded indica se a consulta deu certo. Com SOFT_ERRORS os erros de
+// negócio também têm status 200, então o corpo é verificado.
+func (p *probeRecorder) succeeded() bool {
+	if p.status != http.StatusOK {
+		return false
+	}
+	var body struct {
+		Error string `json:"error"`
+	}
+	return json.Unmarshal(p.body.Bytes(), &body) == nil && body.Error == ""
+}
+
+// handleProbe atende GET /probe?cep=..., no padrão do blackbox exporter: faz
+// a consulta completa do CEP, como em GET /cep/{cep}, e responde métricas
+// Prometheus do resultado em vez do JSON. O status é 200 mesmo quando a
+// consulta falha; a falha aparece em probe_success.
+func handleProbe(w http.ResponseWriter, r *http.Request) {
+	cep := r.URL.Query().Get("cep")
+	if cep == "" {
+		writeJSONError(w, http.StatusBadRequest, "cep parameter is required", "missing_cep")
+		return
+	}
+
+	start := time.Now()
+	ctx, span := otel.Tracer("service-a").Start(withPhaseTimings(r.Context()), "probe")
+	recordRequestSpan(ctx, span)
+	setAttributes(span, attribute.String("cep", cep))
+
+	// A consulta não herda os parâmetros nem os cabeçalhos da sonda
+	probeReq := r.Clone(ctx)
+	probeReq.URL.RawQuery = ""
+	probeReq.Header = make(http.Header)
+
+	var rec probeRecorder
+	resolveCEP(ctx, &rec, probeReq, CEPRequest{CEP: cep})
+	duration := time.Since(start)
+
+	success := 0.0
+	if rec.succeeded() {
+		success = 1
+	} else {
+		span.SetStatus(codes.Error, "Probe failed")
+	}
+	setAttributes(span, attribute.Int("probe.status_code", rec.status))
+	span.End()
+
+	registry := prometheus.NewRegistry()
+	probeSuccess := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "probe_success",
+		Help: "Se a consulta completa do CEP teve sucesso (1) ou não (0).",
+	})
+	probeDuration := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "probe_duration_seconds",
+		Help: "Duração da consulta completa do CEP.",
+	})
+	probeStatus := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "probe_http_status_code",
+		Help: "Status HTTP que a consulta teria devolvido ao cliente.",
+	})
+	phaseDurations := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "probe_phase_duration_seconds",
+		Help: "Duração de cada fase da consulta.",
+	}, []string{"phase"})
+	registry.MustRegister(probeSuccess, probeDuration, probeStatus, phaseDurations)
+
+	probeSuccess.Set(success)
+	probeDuration.Set(duration.Seconds())
+	probeStatus.Set(float64(rec.status))
+	for _, phase := range phaseTimingsFrom(ctx).list() {
+		phaseDurations.WithLabelValues(phase.name).Set(phase.duration.Seconds())
+	}
+
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
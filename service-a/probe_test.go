@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func getProbe(t *testing.T, header ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/probe?cep=01001000", nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	return serve(r)
+}
+
+func assertProbeMetric(t *testing.T, rec *httptest.ResponseRecorder, line string) {
+	t.Helper()
+	if !strings.Contains(rec.Body.String(), "\n"+line+"\n") {
+		t.Errorf("probe output lacks %q:\n%s", line, rec.Body.String())
+	}
+}
+
+func TestProbeReportsResult(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		status  int
+		body    string
+		success string
+		code    string
+	}{
+		{"success", http.StatusOK, serviceBTemperature, "probe_success 1", "probe_http_status_code 200"},
+		{"not found", http.StatusNotFound, `{"schema_version":"1.1","error":"can not find zipcode","code":"zipcode_not_found"}`, "probe_success 0", "probe_http_status_code 404"},
+		{"upstream failure", http.StatusInternalServerError, `{"schema_version":"1.1","error":"failed to fetch temperature","code":"weather_fetch_failed"}`, "probe_success 0", "probe_http_status_code 500"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			setupWithServiceB(t, tc.status, tc.body, "PROBE_ENABLED", "true")
+
+			rec := getProbe(t)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+			}
+			assertProbeMetric(t, rec, tc.success)
+			assertProbeMetric(t, rec, tc.code)
+			if !strings.Contains(rec.Body.String(), `probe_phase_duration_seconds{phase="call-service-b"}`) {
+				t.Errorf("probe output lacks the call-service-b phase:\n%s", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestProbeRequiresAPIKey(t *testing.T) {
+	stub := setupWithServiceB(t, http.StatusOK, serviceBTemperature,
+		"PROBE_ENABLED", "true",
+		"API_KEYS", "monitoring:probe-key",
+	)
+
+	assertError(t, getProbe(t), http.StatusUnauthorized, "invalid_api_key")
+	if n := len(stub.requests()); n != 0 {
+		t.Fatalf("unauthenticated probe reached Service B %d times", n)
+	}
+
+	rec := getProbe(t, "X-API-Key", "probe-key")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+	}
+	assertProbeMetric(t, rec, "probe_success 1")
+}
+
+func TestProbeRequiresHMAC(t *testing.T) {
+	stub := setupWithServiceB(t, http.StatusOK, serviceBTemperature,
+		"PROBE_ENABLED", "true",
+		"AUTH_HMAC_SECRET", "s3cret",
+	)
+
+	assertError(t, getProbe(t), http.StatusUnauthorized, "invalid_signature")
+	if n := len(stub.requests()); n != 0 {
+		t.Errorf("unsigned probe reached Service B %d times", n)
+	}
+}
+
+func TestProbeIsRateLimited(t *testing.T) {
+	stub := setupWithServiceB(t, http.StatusOK, serviceBTemperature,
+		"PROBE_ENABLED", "true",
+		"RATE_LIMIT_RPS", "0.001",
+		"RATE_LIMIT_BURST", "1",
+	)
+
+	if rec := getProbe(t); rec.Code != http.StatusOK {
+		t.Fatalf("first probe status = %d, want 200", rec.Code)
+	}
+	rec := getProbe(t)
+	assertError(t, rec, http.StatusTooManyRequests, "rate_limited")
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("429 without Retry-After")
+	}
+	if n := len(stub.requests()); n != 1 {
+		t.Errorf("Service B received %d requests, want 1", n)
+	}
+}
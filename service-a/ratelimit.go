@@ -0,0 +1,113 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenBucket acumula até burst fichas à taxa de rate por segundo; cada
+// requisição consome uma
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter mantém um tokenBucket por IP de cliente
+type rateLimiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     float64
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+var limiter *rateLimiter
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow consome uma ficha do bucket de key. Sem fichas, devolve também quanto
+// tempo falta para a próxima.
+func (l *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
+
+// sweep descarta, no máximo uma vez por minuto, os buckets parados há tempo
+// suficiente para estarem cheios: recriá-los dá o mesmo resultado
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= refill {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// clientIP identifica o cliente pelo endereço da conexão ou, com
+// TRUST_FORWARDED_FOR, pelo último endereço de X-Forwarded-For, que é o
+// acrescentado pelo proxy à frente do serviço
+func clientIP(r *http.Request) string {
+	if cfg.TrustForwardedFor {
+		if header := r.Header.Get("X-Forwarded-For"); header != "" {
+			hops := strings.Split(header, ",")
+			if ip := strings.TrimSpace(hops[len(hops)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// rateLimit limita as requisições por IP quando RATE_LIMIT_RPS está definido,
+// respondendo 429 com Retry-After ao esgotar as fichas
+func rateLimit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if limiter == nil {
+			next(w, r)
+			return
+		}
+		if ok, wait := limiter.allow(clientIP(r), time.Now()); !ok {
+			retryAfter := int(math.Ceil(wait.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+			writeJSONError(w, http.StatusTooManyRequests, "rate limit exceeded", "rate_limited")
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// postCEPFrom faz o POST /cep a partir do endereço remoteAddr, com o
+// X-Forwarded-For informado (quando não vazio)
+func postCEPFrom(t *testing.T, remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/cep", strings.NewReader(`{"cep": "01001000"}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		r.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	return serve(r)
+}
+
+func TestRateLimitPastBurst(t *testing.T) {
+	stub := setupWithServiceB(t, http.StatusOK, serviceBTemperature, "RATE_LIMIT_RPS", "1", "RATE_LIMIT_BURST", "3")
+
+	for i := range 3 {
+		if rec := postCEPFrom(t, "192.0.2.1:1234", ""); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200 within the burst", i+1, rec.Code)
+		}
+	}
+	rec := postCEPFrom(t, "192.0.2.1:1234", "")
+	assertError(t, rec, http.StatusTooManyRequests, "rate_limited")
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+	if n := len(stub.requests()); n != 3 {
+		t.Errorf("Service B received %d requests, want 3", n)
+	}
+
+	// Outro cliente tem seu próprio bucket
+	if rec := postCEPFrom(t, "192.0.2.2:1234", ""); rec.Code != http.StatusOK {
+		t.Errorf("other client: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestRateLimitForwardedFor(t *testing.T) {
+	tests := []struct {
+		trust string
+		// Com TRUST_FORWARDED_FOR, clientes atrás do mesmo proxy são contados
+		// separadamente; sem ele, todos dividem o bucket do proxy
+		wantSecond int
+	}{
+		{"true", http.StatusOK},
+		{"false", http.StatusTooManyRequests},
+	}
+	for _, tc := range tests {
+		t.Run("TRUST_FORWARDED_FOR="+tc.trust, func(t *testing.T) {
+			setupWithServiceB(t, http.StatusOK, serviceBTemperature,
+				"RATE_LIMIT_RPS", "1", "RATE_LIMIT_BURST", "1", "TRUST_FORWARDED_FOR", tc.trust)
+
+			if rec := postCEPFrom(t, "10.0.0.1:1234", "198.51.100.1"); rec.Code != http.StatusOK {
+				t.Fatalf("first client: status = %d, want 200", rec.Code)
+			}
+			// O primeiro salto é informado pelo cliente e não deve ser usado
+			if rec := postCEPFrom(t, "10.0.0.1:1234", "198.51.100.1, 198.51.100.2"); rec.Code != tc.wantSecond {
+				t.Errorf("second client: status = %d, want %d", rec.Code, tc.wantSecond)
+			}
+		})
+	}
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	setupWithServiceB(t, http.StatusOK, serviceBTemperature, "RATE_LIMIT_BURST", "1")
+
+	for i := range 5 {
+		if rec := postCEPFrom(t, "192.0.2.1:1234", ""); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200 with RATE_LIMIT_RPS unset", i+1, rec.Code)
+		}
+	}
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	l := newRateLimiter(2, 2)
+	now := time.Now()
+
+	for i := range 2 {
+		if ok, _ := l.allow("client", now); !ok {
+			t.Fatalf("request %d denied within the burst", i+1)
+		}
+	}
+	ok, wait := l.allow("client", now)
+	if ok || wait != 500*time.Millisecond {
+		t.Fatalf("allow past burst = %v, %v, want denied with 500ms wait", ok, wait)
+	}
+	if ok, _ := l.allow("client", now.Add(500*time.Millisecond)); !ok {
+		t.Error("request denied after a token was refilled")
+	}
+	// O bucket não passa de burst, por mais que fique parado
+	later := now.Add(time.Hour)
+	for i := range 2 {
+		if ok, _ := l.allow("client", later); !ok {
+			t.Fatalf("request %d denied after a full refill", i+1)
+		}
+	}
+	if ok, _ := l.allow("client", later); ok {
+		t.Error("bucket refilled past its burst")
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// ErrorResponse é o corpo de todas as respostas de erro: a mensagem legível e
+// um código estável para tratamento pelos clientes
+type ErrorResponse struct {
+	SchemaVersion string `json:"schema_version"`
+	Error         string `json:"error"`
+	Code          string `json:"code"`
+}
+
+// writeJSON serializa body com o status informado e devolve o número de bytes
+// escritos
+func writeJSON(w http.ResponseWriter, status int, body any) (int, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	return w.Write(append(payload, '\n'))
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message, code string) {
+	if _, err := writeJSON(w, status, ErrorResponse{SchemaVersion: schemaVersion, Error: message, Code: code}); err != nil {
+		slog.Error("Failed to write error response", "error", err)
+	}
+}
+
+// methodNotAllowed responde 405 com o cabeçalho Allow, para as rotas que só
+// aceitam os métodos informados
+func methodNotAllowed(allowed ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed", "method_not_allowed")
+	}
+}
+
+// limitBody limita o corpo da requisição a MAX_BODY_BYTES, respondendo 413
+// de imediato quando o Content-Length declarado já passa do limite. Corpos
+// sem Content-Length são interrompidos na leitura (ver writeBodyError).
+func limitBody(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > cfg.MaxBodyBytes {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, "request body too large", "request_body_too_large")
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, cfg.MaxBodyBytes)
+		next(w, r)
+	}
+}
+
+// bodyFieldError é um corpo de requisição bem formado, mas com campos
+// inválidos (desconhecidos ou ausentes), respondido com código próprio
+type bodyFieldError struct {
+	message string
+	code    string
+}
+
+func (e *bodyFieldError) Error() string { return e.message }
+
+func unknownFieldError(name string) error {
+	return &bodyFieldError{message: fmt.Sprintf("unknown field %q", name), code: "unknown_field"}
+}
+
+var errMissingCEP = &bodyFieldError{message: "missing cep", code: "missing_cep"}
+
+// writeBodyError responde a uma falha ao ler ou decodificar o corpo: 413 se
+// ele excedeu MAX_BODY_BYTES, 400 nos demais casos
+func writeBodyError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, "request body too large", "request_body_too_large")
+		return
+	}
+	var fieldErr *bodyFieldError
+	if errors.As(err, &fieldErr) {
+		writeJSONError(w, http.StatusBadRequest, fieldErr.message, fieldErr.code)
+		return
+	}
+	writeJSONError(w, http.StatusBadRequest, "invalid request body", "invalid_request_body")
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// phaseTimings acumula a duração de cada fase de uma requisição, para o log
+// de requisições lentas e as métricas de /probe
+type phaseTimings struct {
+	mu     sync.Mutex
+	phases []phaseDuration
+}
+
+type phaseDuration struct {
+	name     string
+	duration time.Duration
+}
+
+type phaseTimingsCtxKey struct{}
+
+func withPhaseTimings(ctx context.Context) context.Context {
+	return context.WithValue(ctx, phaseTimingsCtxKey{}, &phaseTimings{})
+}
+
+func phaseTimingsFrom(ctx context.Context) *phaseTimings {
+	t, _ := ctx.Value(phaseTimingsCtxKey{}).(*phaseTimings)
+	return t
+}
+
+func (t *phaseTimings) add(name string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.phases = append(t.phases, phaseDuration{name: name, duration: d})
+}
+
+// list devolve uma cópia das fases registradas, na ordem em que terminaram
+func (t *phaseTimings) list() []phaseDuration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]phaseDuration(nil), t.phases...)
+}
+
+func (t *phaseTimings) String() string {
+	phases := t.list()
+	parts := make([]string, len(phases))
+	for i, p := range phases {
+		parts[i] = fmt.Sprintf("%s=%v", p.name, p.duration.Round(time.Millisecond))
+	}
+	return strings.Join(parts, " ")
+}
+
+// timedSpan registra a duração da fase em phaseTimings ao ser encerrado
+type timedSpan struct {
+	trace.Span
+	name    string
+	start   time.Time
+	timings *phaseTimings
+}
+
+func (s timedSpan) End(options ...trace.SpanEndOption) {
+	s.timings.add(s.name, time.Since(s.start))
+	s.Span.End(options...)
+}
+
+// logSlowRequest registra um aviso com as durações das fases e o trace ID
+// quando a requisição passa de SLOW_REQUEST_THRESHOLD_MS
+func logSlowRequest(ctx context.Context, start time.Time) {
+	threshold := time.Duration(cfg.SlowRequestThresholdMS) * time.Millisecond
+	elapsed := time.Since(start)
+	if threshold <= 0 || elapsed <= threshold {
+		return
+	}
+
+	var phases string
+	if t := phaseTimingsFrom(ctx); t != nil {
+		phases = t.String()
+	}
+	slog.WarnContext(ctx, "slow request",
+		"total_ms", elapsed.Milliseconds(),
+		"phases", phases,
+	)
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func spanNames(spans []sdktrace.ReadOnlySpan) []string {
+	names := make([]string, len(spans))
+	for i, s := range spans {
+		names[i] = s.Name()
+	}
+	return names
+}
+
+func TestTraceVerbosity(t *testing.T) {
+	setupWithServiceB(t, http.StatusOK, serviceBTemperature)
+	spans := recordSpans(t)
+	if rec := postCEP(t, `{"cep": "01001000"}`); rec.Code != http.StatusOK {
+		t.Fatalf("full: status = %d (body %s)", rec.Code, rec.Body.String())
+	}
+	full := spanNames(spans.Ended())
+	for _, phase := range []string{"validate-cep", "call-service-b"} {
+		if !slices.Contains(full, phase) {
+			t.Errorf("full: spans = %v, want a %s span", full, phase)
+		}
+	}
+
+	setupWithServiceB(t, http.StatusOK, serviceBTemperature, "TRACE_VERBOSITY", "minimal")
+	spans.Reset()
+	if rec := postCEP(t, `{"cep": "01001000"}`); rec.Code != http.StatusOK {
+		t.Fatalf("minimal: status = %d (body %s)", rec.Code, rec.Body.String())
+	}
+	ended := spans.Ended()
+	minimal := spanNames(ended)
+	if len(minimal) >= len(full) {
+		t.Fatalf("minimal: spans = %v, want fewer than full %v", minimal, full)
+	}
+	for _, phase := range []string{"validate-cep", "call-service-b"} {
+		if slices.Contains(minimal, phase) {
+			t.Errorf("minimal: spans = %v, want no %s span", minimal, phase)
+		}
+	}
+
+	// As fases viram eventos no span do handler
+	var events []string
+	for _, s := range ended {
+		for _, e := range s.Events() {
+			events = append(events, e.Name)
+		}
+	}
+	for _, want := range []string{"validate-cep start", "validate-cep end", "call-service-b start", "call-service-b end"} {
+		if !slices.Contains(events, want) {
+			t.Errorf("minimal: events = %v, want %q", events, want)
+		}
+	}
+}
+
+// failingExporter simula um destino de traces fora do ar
+type failingExporter struct{}
+
+func (failingExporter) ExportSpans(context.Context, []sdktrace.ReadOnlySpan) error {
+	return errors.New("collector unavailable")
+}
+
+func (failingExporter) Shutdown(context.Context) error { return nil }
+
+// memoryExporter guarda os spans exportados mesmo depois do Shutdown, que no
+// InMemoryExporter descarta o que foi recebido
+type memoryExporter struct {
+	*tracetest.InMemoryExporter
+}
+
+func (memoryExporter) Shutdown(context.Context) error { return nil }
+
+func TestTracerProviderExportsToAllExporters(t *testing.T) {
+	setupTest(t)
+	zipkin, otlp := memoryExporter{tracetest.NewInMemoryExporter()}, memoryExporter{tracetest.NewInMemoryExporter()}
+	tp, err := newTracerProvider([]sdktrace.SpanExporter{zipkin, failingExporter{}, otlp}, 1)
+	if err != nil {
+		t.Fatalf("newTracerProvider: %v", err)
+	}
+
+	_, span := tp.Tracer("test").Start(context.Background(), "handleCEP")
+	span.End()
+	// Como no encerramento do serviço: o exporter fora do ar não impede a
+	// entrega aos demais
+	tp.Shutdown(context.Background())
+
+	for name, exporter := range map[string]memoryExporter{"first": zipkin, "second": otlp} {
+		spans := exporter.GetSpans()
+		if len(spans) != 1 || spans[0].Name != "handleCEP" {
+			t.Errorf("%s exporter got %d spans, want handleCEP", name, len(spans))
+		}
+	}
+}
+
+// findSpan devolve o span encerrado com o nome informado
+func findSpan(t *testing.T, spans []sdktrace.ReadOnlySpan, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	for _, s := range spans {
+		if s.Name() == name {
+			return s
+		}
+	}
+	t.Fatalf("no %s span in %v", name, spanNames(spans))
+	return nil
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key string) (attribute.Value, bool) {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestContentLengthAttributes(t *testing.T) {
+	setupWithServiceB(t, http.StatusOK, serviceBTemperature)
+	spans := recordSpans(t)
+
+	const body = `{"cep": "01001000"}`
+	rec := postCEP(t, body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (body %s)", rec.Code, rec.Body.String())
+	}
+
+	span := findSpan(t, spans.Ended(), "handleCEP")
+	for key, want := range map[string]int64{
+		"http.request_content_length":  int64(len(body)),
+		"http.response_content_length": int64(rec.Body.Len()),
+	} {
+		if got, ok := spanAttribute(span, key); !ok || got.AsInt64() != want {
+			t.Errorf("%s = %v, want %d", key, got.Emit(), want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		in   string
+		max  int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"longer than ten", 10, "longer th…"},
+		// Conta caracteres, não bytes
+		{"São Paulo, SP", 5, "São …"},
+		{"abc", 1, "…"},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.in, tt.max); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.in, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestSetAttributesTruncatesStrings(t *testing.T) {
+	setupTest(t, "TRACE_ATTRIBUTE_MAX_LENGTH", "8")
+	spans := recordSpans(t)
+
+	_, span := otel.Tracer("test").Start(context.Background(), "truncation")
+	setAttributes(span,
+		attribute.String("error.body", strings.Repeat("x", 300)),
+		attribute.String("city", "Recife"),
+		attribute.Int("http.status_code", 502),
+	)
+	span.End()
+
+	got := spans.Ended()[0]
+	if v, _ := spanAttribute(got, "error.body"); v.AsString() != "xxxxxxx…" {
+		t.Errorf("error.body = %q, want 7 characters and an ellipsis", v.AsString())
+	}
+	if v, _ := spanAttribute(got, "city"); v.AsString() != "Recife" {
+		t.Errorf("city = %q, want it untouched", v.AsString())
+	}
+	if v, _ := spanAttribute(got, "http.status_code"); v.AsInt64() != 502 {
+		t.Errorf("http.status_code = %v, want 502", v.Emit())
+	}
+}
+
+func TestTracerProviderResourceRegion(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          []string
+		region, zone string
+	}{
+		{"configured", []string{"DEPLOY_REGION", "sa-east-1", "DEPLOY_ZONE", "sa-east-1a"}, "sa-east-1", "sa-east-1a"},
+		{"unset", nil, "", ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setupTest(t, tc.env...)
+			exporter := memoryExporter{tracetest.NewInMemoryExporter()}
+			tp, err := newTracerProvider([]sdktrace.SpanExporter{exporter}, 1)
+			if err != nil {
+				t.Fatalf("newTracerProvider: %v", err)
+			}
+			_, span := tp.Tracer("test").Start(context.Background(), "request")
+			span.End()
+			tp.Shutdown(context.Background())
+
+			spans := exporter.GetSpans()
+			if len(spans) != 1 {
+				t.Fatalf("exported %d spans, want 1", len(spans))
+			}
+			res := spans[0].Resource
+			for key, want := range map[attribute.Key]string{
+				"cloud.region":            tc.region,
+				"cloud.availability_zone": tc.zone,
+			} {
+				got, ok := res.Set().Value(key)
+				if want == "" {
+					if ok {
+						t.Errorf("resource has %s = %s, want it unset", key, got.AsString())
+					}
+					continue
+				}
+				if got.AsString() != want {
+					t.Errorf("resource %s = %q, want %q", key, got.AsString(), want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// temperatureUnits indica as escalas pedidas pelo cliente com ?units=
+type temperatureUnits struct {
+	C, F, K bool
+}
+
+var allUnits = temperatureUnits{C: true, F: true, K: true}
+
+// parseUnits lê a lista de escalas de ?units= (ex.: "c,f"); vazia, devolve
+// as três
+func parseUnits(raw string) (temperatureUnits, error) {
+	if raw == "" {
+		return allUnits, nil
+	}
+
+	var units temperatureUnits
+	for _, code := range strings.Split(raw, ",") {
+		switch strings.ToLower(strings.TrimSpace(code)) {
+		case "c":
+			units.C = true
+		case "f":
+			units.F = true
+		case "k":
+			units.K = true
+		default:
+			return temperatureUnits{}, fmt.Errorf("invalid units parameter")
+		}
+	}
+	return units, nil
+}
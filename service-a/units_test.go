@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCEPUnits(t *testing.T) {
+	routes := []struct {
+		name string
+		do   func(units string) *httptest.ResponseRecorder
+	}{
+		{"POST", func(units string) *httptest.ResponseRecorder {
+			r := httptest.NewRequest(http.MethodPost, "/cep?units="+units, strings.NewReader(`{"cep": "01001000"}`))
+			r.Header.Set("Content-Type", "application/json")
+			return serve(r)
+		}},
+		{"GET", func(units string) *httptest.ResponseRecorder {
+			return serve(httptest.NewRequest(http.MethodGet, "/cep/01001000?units="+units, nil))
+		}},
+	}
+	for _, route := range routes {
+		// As escalas válidas seguem ao Service B, que monta a resposta
+		for _, units := range []string{"c,f", "k"} {
+			t.Run(route.name+" "+units, func(t *testing.T) {
+				stub := setupWithServiceB(t, http.StatusOK, serviceBTemperature)
+
+				if rec := route.do(units); rec.Code != http.StatusOK {
+					t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+				}
+				reqs := stub.requests()
+				if len(reqs) != 1 || reqs[0].query != "units="+units {
+					t.Errorf("Service B requests = %+v, want one with units=%s", reqs, units)
+				}
+			})
+		}
+
+		t.Run(route.name+" unknown code", func(t *testing.T) {
+			stub := setupWithServiceB(t, http.StatusOK, serviceBTemperature)
+
+			assertError(t, route.do("c,x"), http.StatusBadRequest, "invalid_units")
+			if n := len(stub.requests()); n != 0 {
+				t.Errorf("Service B received %d requests, want 0", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// ResponseMeta traz informações de diagnóstico da requisição, exibidas com
+// ?debug=true
+type ResponseMeta struct {
+	// Total de tentativas feitas a cada provedor, somando as repetições
+	Attempts map[string]int `json:"attempts"`
+}
+
+// attemptRecorder conta as tentativas de chamada a cada provedor ao longo de
+// uma requisição; consultas paralelas (?nearby) somam no mesmo provedor
+type attemptRecorder struct {
+	mu       sync.Mutex
+	attempts map[string]int
+}
+
+type attemptsCtxKey struct{}
+
+func withAttempts(ctx context.Context) context.Context {
+	return context.WithValue(ctx, attemptsCtxKey{}, &attemptRecorder{attempts: map[string]int{}})
+}
+
+func recordAttempt(ctx context.Context, provider string) {
+	if rec, ok := ctx.Value(attemptsCtxKey{}).(*attemptRecorder); ok {
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		rec.attempts[provider]++
+	}
+}
+
+func attemptsFrom(ctx context.Context) map[string]int {
+	rec, ok := ctx.Value(attemptsCtxKey{}).(*attemptRecorder)
+	if !ok {
+		return map[string]int{}
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	attempts := make(map[string]int, len(rec.attempts))
+	for provider, n := range rec.attempts {
+		attempts[provider] = n
+	}
+	return attempts
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDebugReportsRetriedAttempts(t *testing.T) {
+	current, err := os.ReadFile("testdata/weatherapi_current.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Falha a primeira consulta e atende as demais
+	var calls atomic.Int32
+	weatherCalls := setupWithWeatherAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(current)
+	})
+
+	rec := getTemperature(t, "/temperature/01001000?debug=true")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+	}
+	assertTemperatureSchema(t, rec)
+	got := decodeBody[TemperatureResponse](t, rec)
+	if got.Meta == nil {
+		t.Fatalf("response lacks meta: %s", rec.Body.String())
+	}
+	if n := got.Meta.Attempts["weatherapi"]; n != 2 {
+		t.Errorf("meta.attempts.weatherapi = %d, want 2", n)
+	}
+	if n := got.Meta.Attempts["viacep"]; n != 1 {
+		t.Errorf("meta.attempts.viacep = %d, want 1", n)
+	}
+	if n := weatherCalls.Load(); n != 2 {
+		t.Errorf("WeatherAPI received %d calls, want 2", n)
+	}
+}
+
+func TestAttemptsOmittedWithoutDebug(t *testing.T) {
+	setupWithWeatherAPI(t, nil)
+
+	got := decodeBody[TemperatureResponse](t, getTemperature(t, "/temperature/01001000"))
+	if got.Meta != nil || got.Sources != nil {
+		t.Errorf("meta/sources = %+v/%+v, want both omitted without ?debug=true", got.Meta, got.Sources)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AuditEntry registra uma resolução de CEP bem-sucedida, mesmo que a consulta
+// de clima falhe depois. Não guarda dados do cliente além do próprio CEP.
+type AuditEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	CEP       string    `json:"cep"`
+	City      string    `json:"city"`
+	Providers *Sources  `json:"providers"`
+	TraceID   string    `json:"trace_id,omitempty"`
+}
+
+// AuditSink recebe as entradas de auditoria; com AUDIT_LOG=true o padrão é
+// logAuditSink, e outros destinos só precisam implementar Record
+type AuditSink interface {
+	Record(entry AuditEntry)
+}
+
+// logAuditSink escreve cada entrada como uma linha JSON no log do serviço
+type logAuditSink struct{}
+
+func (logAuditSink) Record(entry AuditEntry) {
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		slog.Error("Failed to encode audit entry", "error", err)
+		return
+	}
+	slog.Info("audit", "entry", json.RawMessage(payload))
+}
+
+// auditSink fica nil quando AUDIT_LOG está desligado
+var auditSink AuditSink
+
+// auditResolution registra a resolução de cep em city no auditSink, quando
+// configurado
+func auditResolution(ctx context.Context, cep, city string) {
+	if auditSink == nil {
+		return
+	}
+	entry := AuditEntry{
+		Timestamp: time.Now().UTC(),
+		CEP:       cep,
+		City:      city,
+		Providers: sourcesFrom(ctx),
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		entry.TraceID = sc.TraceID().String()
+	}
+	auditSink.Record(entry)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// memoryAuditSink guarda as entradas em memória para os testes
+type memoryAuditSink struct {
+	mu      sync.Mutex
+	entries []AuditEntry
+}
+
+func (s *memoryAuditSink) Record(entry AuditEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, entry)
+}
+
+func (s *memoryAuditSink) recorded() []AuditEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]AuditEntry(nil), s.entries...)
+}
+
+func TestAuditEntryPerResolution(t *testing.T) {
+	setupWithViaCEP(t, "AUDIT_LOG", "true")
+	recordSpans(t)
+	sink := &memoryAuditSink{}
+	auditSink = sink
+
+	for _, body := range []string{`{"cep": "01001000"}`, `{"cep": "01001000", "requested_cep": "01001-000"}`} {
+		if rec := postTemperature(t, "/temperature", body); rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d (body %s)", body, rec.Code, rec.Body.String())
+		}
+	}
+	// Resoluções que falham não são auditadas
+	assertError(t, getTemperature(t, "/temperature/99999999"), http.StatusNotFound, "zipcode_not_found")
+
+	entries := sink.recorded()
+	if len(entries) != 2 {
+		t.Fatalf("got %d audit entries, want 2", len(entries))
+	}
+	for i, e := range entries {
+		if e.CEP != "01001000" || e.City != "São Paulo" {
+			t.Errorf("entry %d = %s/%s, want the normalized CEP and São Paulo", i, e.CEP, e.City)
+		}
+		if e.Timestamp.IsZero() || e.TraceID == "" {
+			t.Errorf("entry %d lacks timestamp or trace_id: %+v", i, e)
+		}
+		if e.Providers == nil || e.Providers.CEP == nil || e.Providers.CEP.Provider != "viacep" {
+			t.Errorf("entry %d providers = %+v, want the viacep CEP source", i, e.Providers)
+		}
+	}
+	if trace0, trace1 := entries[0].TraceID, entries[1].TraceID; trace0 == trace1 {
+		t.Errorf("both entries share trace_id %s, want one per request", trace0)
+	}
+}
+
+func TestAuditEntryWhateverTheWeatherOutcome(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T)
+		do    func(t *testing.T) int
+	}{
+		{"weather failure", func(t *testing.T) {
+			setupWithWeatherAPI(t, func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, `{"error":{"code":9999,"message":"Internal application error."}}`, http.StatusInternalServerError)
+			}, "AUDIT_LOG", "true")
+		}, func(t *testing.T) int {
+			return getTemperature(t, "/temperature/01001000").Code
+		}},
+		{"skipped city", func(t *testing.T) {
+			setupWithViaCEP(t, "AUDIT_LOG", "true", "WEATHER_SKIP_CITIES", "São Paulo")
+		}, func(t *testing.T) int {
+			return getTemperature(t, "/temperature/01001000").Code
+		}},
+		{"not modified", func(t *testing.T) {
+			setupWithObservation(t, "AUDIT_LOG", "true")
+		}, func(t *testing.T) int {
+			etag := getWithETag(t, "/temperature/01001000", "").Header().Get("ETag")
+			return getWithETag(t, "/temperature/01001000", etag).Code
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup(t)
+			sink := &memoryAuditSink{}
+			auditSink = sink
+
+			status := tt.do(t)
+			if status == http.StatusOK {
+				t.Fatalf("status = 200, want the %s outcome", tt.name)
+			}
+			entries := sink.recorded()
+			if len(entries) == 0 {
+				t.Fatalf("no audit entry for status %d", status)
+			}
+			last := entries[len(entries)-1]
+			if last.CEP != "01001000" || last.City != "São Paulo" {
+				t.Errorf("entry = %s/%s, want 01001000/São Paulo", last.CEP, last.City)
+			}
+		})
+	}
+}
+
+func TestAuditLogSink(t *testing.T) {
+	logs := captureLogs(t)
+	setupWithViaCEP(t, "AUDIT_LOG", "true")
+
+	if rec := getTemperature(t, "/temperature/01001000"); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (body %s)", rec.Code, rec.Body.String())
+	}
+	audit := findLogs(logs(), "audit")
+	if len(audit) != 1 {
+		t.Fatalf("got %d audit log lines, want 1", len(audit))
+	}
+	raw, _ := json.Marshal(audit[0]["entry"])
+	var entry AuditEntry
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		t.Fatalf("invalid audit entry %s: %v", raw, err)
+	}
+	if entry.CEP != "01001000" || entry.City != "São Paulo" {
+		t.Errorf("audit entry = %s/%s, want 01001000/São Paulo", entry.CEP, entry.City)
+	}
+}
+
+func TestAuditLogDisabled(t *testing.T) {
+	logs := captureLogs(t)
+	setupWithViaCEP(t)
+
+	if rec := getTemperature(t, "/temperature/01001000"); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (body %s)", rec.Code, rec.Body.String())
+	}
+	if audit := findLogs(logs(), "audit"); len(audit) != 0 {
+		t.Errorf("got %d audit log lines with AUDIT_LOG off, want 0", len(audit))
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+type BatchRequest struct {
+	CEPs []string `json:"ceps"`
+}
+
+// BatchItem é o resultado de um CEP do lote: a resposta de temperatura ou o
+// erro, com o mesmo status que a consulta individual teria
+type BatchItem struct {
+	CEP    string          `json:"cep"`
+	Status int             `json:"status"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *BatchError     `json:"error,omitempty"`
+}
+
+type BatchError struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// Resultados na mesma ordem dos CEPs enviados
+type BatchResponse struct {
+	SchemaVersion string      `json:"schema_version"`
+	Results       []BatchItem `json:"results"`
+}
+
+// responseBuffer guarda em memória a resposta de um item do lote
+type responseBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *responseBuffer) Header() http.Header {
+	if b.header == nil {
+		b.header = make(http.Header)
+	}
+	return b.header
+}
+
+func (b *responseBuffer) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	b.WriteHeader(http.StatusOK)
+	return b.body.Write(p)
+}
+
+// handleTemperatureBatch atende POST /temperature/batch, resolvendo os CEPs
+// em paralelo com até BATCH_CONCURRENCY consultas simultâneas
+func handleTemperatureBatch(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	ctx, span := startRequest(r, "handleTemperatureBatch")
+	defer span.End()
+	defer logSlowRequest(ctx, start)
+
+	var req BatchRequest
+	reqBody := &countingReader{Reader: r.Body}
+	err := json.NewDecoder(reqBody).Decode(&req)
+	setAttributes(span, attribute.Int64("http.request_content_length", reqBody.n))
+	if err != nil {
+		span.SetStatus(codes.Error, "Invalid request body")
+		writeBodyError(w, err)
+		return
+	}
+	if len(req.CEPs) == 0 {
+		span.SetStatus(codes.Error, "Invalid request body")
+		writeJSONError(w, http.StatusBadRequest, "invalid request body", "invalid_request_body")
+		return
+	}
+	setAttributes(span, attribute.Int("batch.size", len(req.CEPs)))
+	if len(req.CEPs) > cfg.BatchMaxSize {
+		span.SetStatus(codes.Error, "Batch too large")
+		writeJSONError(w, http.StatusBadRequest, "too many ceps", "batch_too_large")
+		return
+	}
+
+	// Os itens são sempre JSON, mesmo que o cliente aceite GeoJSON, e nunca
+	// respostas condicionais: um 304 não tem corpo para incluir no lote
+	itemReq := r.Clone(ctx)
+	itemReq.Header.Del("Accept")
+	for name := range itemReq.Header {
+		if strings.HasPrefix(name, "If-") {
+			itemReq.Header.Del(name)
+		}
+	}
+
+	results := make([]BatchItem, len(req.CEPs))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(cfg.BatchConcurrency, len(req.CEPs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = resolveBatchItem(ctx, itemReq, i, req.CEPs[i])
+			}
+		}()
+	}
+	for i := range req.CEPs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	n, err := writeJSON(w, http.StatusOK, BatchResponse{SchemaVersion: schemaVersion, Results: results})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to write response")
+	}
+	setAttributes(span, attribute.Int("http.response_content_length", n))
+}
+
+// resolveBatchItem passa um CEP do lote pelo mesmo fluxo da consulta
+// individual, sob um span próprio
+func resolveBatchItem(ctx context.Context, r *http.Request, index int, cep string) BatchItem {
+	ctx, span := otel.Tracer("service-b").Start(ctx, "batch-item")
+	defer span.End()
+
+	setAttributes(span, attribute.Int("batch.index", index))
+
+	item := BatchItem{CEP: cep}
+	if !isValidCEP(cep) {
+		span.SetStatus(codes.Error, "Invalid zipcode")
+		item.Status = http.StatusUnprocessableEntity
+		item.Error = &BatchError{Error: "invalid zipcode", Code: "invalid_zipcode"}
+		return item
+	}
+
+	var buf responseBuffer
+	respondTemperature(ctx, &buf, r, CEPRequest{CEP: cep})
+	item.Status = buf.status
+	if buf.status == http.StatusOK {
+		item.Result = bytes.TrimSpace(buf.body.Bytes())
+		return item
+	}
+	item.Error = &BatchError{}
+	if err := json.Unmarshal(buf.body.Bytes(), item.Error); err != nil {
+		item.Error = &BatchError{Error: "internal server error", Code: "internal_error"}
+	}
+	return item
+}
+
+// isValidCEP verifica se o CEP tem exatamente 8 dígitos; os itens do lote não
+// passam pela validação do Service A
+func isValidCEP(cep string) bool {
+	if len(cep) != 8 {
+		return false
+	}
+	for _, c := range cep {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
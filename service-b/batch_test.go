@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newSlowViaCEPStub sobe um ViaCEP falso que conhece qualquer CEP válido e
+// demora delay(cep) para responder. Devolve o maior número de consultas
+// simultâneas observado.
+func newSlowViaCEPStub(t *testing.T, delay func(cep string) time.Duration) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var inFlight, maxInFlight atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+
+		cep := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/ws/"), "/json/")
+		time.Sleep(delay(cep))
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprintf(w, `{"cep": %q, "localidade": "São Paulo", "uf": "SP"}`, cep)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &maxInFlight
+}
+
+func postBatch(t *testing.T, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	return postTemperature(t, "/temperature/batch", body)
+}
+
+func TestBatchPreservesOrder(t *testing.T) {
+	ceps := []string{"01001000", "01002000", "01003000", "01004000"}
+	// Os primeiros CEPs demoram mais, para que terminem por último
+	srv, _ := newSlowViaCEPStub(t, func(cep string) time.Duration {
+		for i, c := range ceps {
+			if c == cep {
+				return time.Duration(len(ceps)-i) * 10 * time.Millisecond
+			}
+		}
+		return 0
+	})
+	setupTest(t, "VIACEP_URL", srv.URL, "BATCH_CONCURRENCY", "4")
+
+	rec := postBatch(t, `{"ceps": ["01001000", "01002000", "01003000", "01004000"]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+	}
+	got := decodeBody[BatchResponse](t, rec)
+	if len(got.Results) != len(ceps) {
+		t.Fatalf("got %d results, want %d", len(got.Results), len(ceps))
+	}
+	for i, item := range got.Results {
+		if item.CEP != ceps[i] || item.Status != http.StatusOK {
+			t.Errorf("results[%d] = %s/%d, want %s/200", i, item.CEP, item.Status, ceps[i])
+		}
+	}
+}
+
+func TestBatchMixedResults(t *testing.T) {
+	calls := setupWithViaCEP(t)
+
+	rec := postBatch(t, `{"ceps": ["01001000", "123", "99999999"]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+	}
+	got := decodeBody[BatchResponse](t, rec)
+	want := []struct {
+		cep    string
+		status int
+		code   string
+	}{
+		{"01001000", http.StatusOK, ""},
+		{"123", http.StatusUnprocessableEntity, "invalid_zipcode"},
+		{"99999999", http.StatusNotFound, "zipcode_not_found"},
+	}
+	if len(got.Results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(got.Results), len(want))
+	}
+	for i, w := range want {
+		item := got.Results[i]
+		if item.CEP != w.cep || item.Status != w.status {
+			t.Errorf("results[%d] = %s/%d, want %s/%d", i, item.CEP, item.Status, w.cep, w.status)
+		}
+		if w.code == "" {
+			if item.Error != nil || !strings.Contains(string(item.Result), `"city":"São Paulo"`) {
+				t.Errorf("results[%d]: result %s, error %v, want São Paulo", i, item.Result, item.Error)
+			}
+			continue
+		}
+		if item.Error == nil || item.Error.Code != w.code || item.Result != nil {
+			t.Errorf("results[%d]: error %v, result %s, want code %s", i, item.Error, item.Result, w.code)
+		}
+	}
+	// O CEP mal formado não chega ao ViaCEP
+	if n := calls.Load(); n != 2 {
+		t.Errorf("ViaCEP calls = %d, want 2", n)
+	}
+}
+
+func TestBatchTooLarge(t *testing.T) {
+	calls := setupWithViaCEP(t, "BATCH_MAX_SIZE", "2")
+
+	assertError(t, postBatch(t, `{"ceps": ["01001000", "01001000", "01001000"]}`), http.StatusBadRequest, "batch_too_large")
+	if n := calls.Load(); n != 0 {
+		t.Errorf("ViaCEP calls = %d, want 0", n)
+	}
+
+	if rec := postBatch(t, `{"ceps": ["01001000", "01001000"]}`); rec.Code != http.StatusOK {
+		t.Errorf("batch at BATCH_MAX_SIZE: status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+	}
+}
+
+func TestBatchConcurrencyLimit(t *testing.T) {
+	srv, maxInFlight := newSlowViaCEPStub(t, func(string) time.Duration { return 20 * time.Millisecond })
+	setupTest(t, "VIACEP_URL", srv.URL, "BATCH_CONCURRENCY", "2")
+
+	ceps := make([]string, 8)
+	for i := range ceps {
+		ceps[i] = fmt.Sprintf(`"0100%d000"`, i+1)
+	}
+	rec := postBatch(t, `{"ceps": [`+strings.Join(ceps, ", ")+`]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+	}
+	if n := maxInFlight.Load(); n != 2 {
+		t.Errorf("max concurrent ViaCEP lookups = %d, want 2 (BATCH_CONCURRENCY)", n)
+	}
+}
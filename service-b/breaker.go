@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var errCircuitOpen = errors.New("circuit open")
+
+type breakerState string
+
+const (
+	breakerClosed   breakerState = "closed"
+	breakerOpen     breakerState = "open"
+	breakerHalfOpen breakerState = "half-open"
+)
+
+// circuitBreaker suspende as chamadas a um provedor após falhas transitórias
+// consecutivas. Passado o tempo de espera, uma única chamada de teste é
+// liberada: se der certo o circuito fecha, senão volta a abrir.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	provider  string
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	state     breakerState
+	openUntil time.Time
+}
+
+func (b *circuitBreaker) allow(ctx context.Context) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.threshold <= 0 || b.state == breakerClosed {
+		return true
+	}
+	now := time.Now()
+	if now.Before(b.openUntil) {
+		return false
+	}
+	// Meio-aberto: bloqueia as demais até o resultado da chamada de teste
+	b.transition(ctx, breakerHalfOpen)
+	b.openUntil = now.Add(b.cooldown)
+	return true
+}
+
+// record registra o resultado de uma chamada; apenas falhas transitórias
+// (ver isRetryable) contam para abrir o circuito
+func (b *circuitBreaker) record(ctx context.Context, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !failed {
+		b.failures = 0
+		b.transition(ctx, breakerClosed)
+		return
+	}
+	b.failures++
+	if b.threshold > 0 && (b.failures >= b.threshold || b.state == breakerHalfOpen) {
+		b.transition(ctx, breakerOpen)
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// currentState devolve o estado do circuito. Um circuito aberto cujo tempo de
+// espera já passou é informado como meio-aberto, pois a próxima chamada será
+// liberada.
+func (b *circuitBreaker) currentState() breakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerOpen && !time.Now().Before(b.openUntil) {
+		return breakerHalfOpen
+	}
+	return b.state
+}
+
+// transition muda o estado (com b.mu já obtido) e registra a mudança como
+// evento no span corrente
+func (b *circuitBreaker) transition(ctx context.Context, to breakerState) {
+	if b.state == to {
+		return
+	}
+	from := b.state
+	b.state = to
+	trace.SpanFromContext(ctx).AddEvent("circuit breaker state change", trace.WithAttributes(
+		attribute.String("circuit.provider", b.provider),
+		attribute.String("circuit.from", string(from)),
+		attribute.String("circuit.to", string(to)),
+	))
+	slog.WarnContext(ctx, "Circuit breaker state change", "provider", b.provider, "from", from, "to", to)
+}
+
+// breakerRegistry mantém um circuitBreaker isolado por provedor, para que a
+// falha de um não bloqueie as chamadas aos demais
+type breakerRegistry struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	breakers  map[string]*circuitBreaker
+}
+
+var breakers *breakerRegistry
+
+func newBreakerRegistry(threshold int, cooldown time.Duration) *breakerRegistry {
+	return &breakerRegistry{
+		threshold: threshold,
+		cooldown:  cooldown,
+		breakers:  make(map[string]*circuitBreaker),
+	}
+}
+
+func (r *breakerRegistry) get(provider string) *circuitBreaker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.breakers[provider]
+	if !ok {
+		b = &circuitBreaker{provider: provider, threshold: r.threshold, cooldown: r.cooldown, state: breakerClosed}
+		r.breakers[provider] = b
+	}
+	return b
+}
+
+// states devolve o estado atual do circuito de cada provedor já consultado
+func (r *breakerRegistry) states() map[string]breakerState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	states := make(map[string]breakerState, len(r.breakers))
+	for provider, b := range r.breakers {
+		states[provider] = b.currentState()
+	}
+	return states
+}
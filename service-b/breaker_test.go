@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBreakerRegistryIsolatesProviders(t *testing.T) {
+	ctx := context.Background()
+	r := newBreakerRegistry(2, time.Minute)
+	if r.get("viacep") != r.get("viacep") {
+		t.Fatal("get returned a new breaker for the same provider")
+	}
+
+	r.get("weatherapi").record(ctx, true)
+	r.get("weatherapi").record(ctx, true)
+
+	states := r.states()
+	if states["weatherapi"] != breakerOpen {
+		t.Errorf("weatherapi = %s, want open after 2 failures", states["weatherapi"])
+	}
+	for _, provider := range []string{"viacep", "brasilapi", "openweathermap"} {
+		if !r.get(provider).allow(ctx) {
+			t.Errorf("%s blocked by the weatherapi breaker", provider)
+		}
+	}
+}
+
+func TestOpenWeatherBreakerDoesNotBlockCEPLookup(t *testing.T) {
+	srv, weatherCalls := newWeatherAPIStub(t, nil)
+	viaCEPCalls := setupWithViaCEP(t,
+		"WEATHER_PROVIDER", "weatherapi",
+		"WEATHER_API_URL", srv.URL+"/v1/current.json",
+		"BREAKER_FAILURE_THRESHOLD", "1",
+	)
+	breakers.get("weatherapi").record(context.Background(), true)
+
+	assertError(t, getTemperature(t, "/temperature/01001000"), http.StatusServiceUnavailable, "weather_service_unavailable")
+	if viaCEPCalls.Load() != 1 {
+		t.Errorf("ViaCEP calls = %d, want 1 despite the open WeatherAPI breaker", viaCEPCalls.Load())
+	}
+	if weatherCalls.Load() != 0 {
+		t.Errorf("WeatherAPI calls = %d, want 0 with its breaker open", weatherCalls.Load())
+	}
+	if state := breakers.get("viacep").currentState(); state != breakerClosed {
+		t.Errorf("viacep breaker = %s, want closed", state)
+	}
+}
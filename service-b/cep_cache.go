@@ -0,0 +1,83 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+)
+
+// cepCache guarda por CEP_CACHE_TTL a localidade resolvida de cada CEP; como
+// CEPs raramente mudam de cidade, evita consultar os provedores a cada acesso
+type cepCache struct {
+	ttl     time.Duration
+	entries *shardedMap[cepCacheEntry]
+}
+
+type cepCacheEntry struct {
+	addr   Address
+	expiry time.Time
+}
+
+var addressCache *cepCache
+
+func newCEPCache(ttl time.Duration, shards int) *cepCache {
+	return &cepCache{
+		ttl:     ttl,
+		entries: newShardedMap[cepCacheEntry](shards),
+	}
+}
+
+func (c *cepCache) get(cep string) (Address, bool) {
+	if c.ttl <= 0 {
+		return Address{}, false
+	}
+
+	var (
+		addr  Address
+		found bool
+	)
+	c.entries.withShard(cep, func(entries map[string]cepCacheEntry) {
+		e, ok := entries[cep]
+		if ok && time.Now().After(e.expiry) {
+			delete(entries, cep)
+			return
+		}
+		addr, found = e.addr, ok
+	})
+	return addr, found
+}
+
+func (c *cepCache) set(cep string, addr Address) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.entries.withShard(cep, func(entries map[string]cepCacheEntry) {
+		entries[cep] = cepCacheEntry{addr: addr, expiry: time.Now().Add(c.ttl)}
+	})
+}
+
+// cleanup remove as entradas vencidas e devolve o tamanho restante e quantas
+// foram removidas
+func (c *cepCache) cleanup() (size, evicted int) {
+	now := time.Now()
+	c.entries.forEachShard(func(entries map[string]cepCacheEntry) {
+		for cep, e := range entries {
+			if now.After(e.expiry) {
+				delete(entries, cep)
+				evicted++
+			}
+		}
+		size += len(entries)
+	})
+	return size, evicted
+}
+
+// runCleanup executa cleanup a cada intervalo e registra o tamanho do cache
+func (c *cepCache) runCleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		size, evicted := c.cleanup()
+		slog.Info("CEP cache cleanup", "size", size, "evicted", evicted)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCEPCacheSkipsSecondLookup(t *testing.T) {
+	calls := setupWithViaCEP(t)
+	recorder := recordSpans(t)
+
+	// O cache é indexado pelo CEP normalizado, e não pelo informado ao Service A
+	for _, body := range []string{`{"cep": "01001000"}`, `{"cep": "01001000", "requested_cep": "01001-000"}`} {
+		if rec := postTemperature(t, "/temperature", body); rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d (body %s)", body, rec.Code, rec.Body.String())
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("ViaCEP calls = %d, want 1", got)
+	}
+
+	var hits int
+	for _, span := range recorder.Ended() {
+		if span.Name() != "fetch-city-from-cep" {
+			continue
+		}
+		if hit, ok := spanAttribute(span, "cache.hit"); ok && hit.AsBool() {
+			hits++
+		}
+	}
+	if hits != 1 {
+		t.Errorf("%d fetch-city-from-cep spans with cache.hit=true, want 1", hits)
+	}
+}
+
+func TestCEPCacheDisabled(t *testing.T) {
+	calls := setupWithViaCEP(t, "CEP_CACHE_TTL", "0s")
+
+	for range 2 {
+		if rec := getTemperature(t, "/temperature/01001000"); rec.Code != http.StatusOK {
+			t.Fatalf("status = %d (body %s)", rec.Code, rec.Body.String())
+		}
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("ViaCEP calls = %d, want 2 with the cache disabled", got)
+	}
+}
+
+func TestCEPCacheExpiry(t *testing.T) {
+	c := newCEPCache(20*time.Millisecond, 4)
+	c.set("01001000", Address{City: "São Paulo"})
+	if _, ok := c.get("01001000"); !ok {
+		t.Fatal("get missed a fresh entry")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := c.get("01001000"); ok {
+		t.Error("get hit an expired entry")
+	}
+}
+
+func TestCEPCacheCleanup(t *testing.T) {
+	c := newCEPCache(20*time.Millisecond, 4)
+	c.set("01001000", Address{City: "São Paulo"})
+	c.set("20040020", Address{City: "Rio de Janeiro"})
+	time.Sleep(30 * time.Millisecond)
+	c.set("30130010", Address{City: "Belo Horizonte"})
+
+	if size, evicted := c.cleanup(); size != 1 || evicted != 2 {
+		t.Errorf("cleanup = size %d, evicted %d, want 1, 2", size, evicted)
+	}
+	if size, evicted := c.cleanup(); size != 1 || evicted != 0 {
+		t.Errorf("second cleanup = size %d, evicted %d, want 1, 0", size, evicted)
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+const brasilAPIBaseURL = "https://brasilapi.com.br"
+
+// CEPResolver consulta um provedor externo para obter a localidade de um CEP.
+// Os erros "invalid zipcode", "can not find zipcode" e "city not found" são
+// respostas definitivas; os demais indicam falha do provedor.
+type CEPResolver interface {
+	Name() string
+	Resolve(ctx context.Context, cep string) (Address, error)
+}
+
+// Provedores consultados em ordem por fetchCityFromCEP, conforme CEP_PROVIDERS
+var cepResolvers []CEPResolver
+
+func newCEPResolvers(names []string) []CEPResolver {
+	resolvers := make([]CEPResolver, 0, len(names))
+	for _, name := range names {
+		switch name {
+		case "viacep":
+			resolvers = append(resolvers, viaCEPResolver{})
+		case "brasilapi":
+			resolvers = append(resolvers, brasilAPIResolver{})
+		}
+	}
+	return resolvers
+}
+
+// isDefinitiveCEPError indica se o provedor respondeu que o CEP é inválido ou
+// não existe; nesses casos não adianta consultar outro provedor
+func isDefinitiveCEPError(err error) bool {
+	return isCEPNotFound(err) || err.Error() == "invalid zipcode"
+}
+
+// getCEP faz a chamada ao provedor, protegida pelo seu circuitBreaker, e
+// converte 400 e 404 nos erros definitivos
+func getCEP(ctx context.Context, provider, url string, policy retryPolicy) (*http.Response, error) {
+	breaker := breakers.get(provider)
+	if !breaker.allow(ctx) {
+		return nil, fmt.Errorf("%s: %w", provider, errCircuitOpen)
+	}
+
+	resp, err := retryGet(ctx, url, policy, breaker)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp, nil
+	case http.StatusBadRequest:
+		resp.Body.Close()
+		return nil, fmt.Errorf("invalid zipcode")
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, fmt.Errorf("can not find zipcode")
+	}
+	resp.Body.Close()
+	return nil, fmt.Errorf("%s returned status %d", provider, resp.StatusCode)
+}
+
+type viaCEPResolver struct{}
+
+func (viaCEPResolver) Name() string { return "viacep" }
+
+func (r viaCEPResolver) Resolve(ctx context.Context, cep string) (Address, error) {
+	tracer := otel.Tracer("service-b")
+	ctx, span := startPhase(ctx, tracer, "resolve-cep-viacep")
+	defer span.End()
+
+	setAttributes(span, attribute.String("api.url", "viacep.com.br"))
+
+	start := time.Now()
+	resp, err := getCEP(ctx, r.Name(), fmt.Sprintf("%s/ws/%s/json/", cfg.ViaCEPURL, cep), retryPolicy{
+		provider:    r.Name(),
+		maxAttempts: cfg.ViaCEPRetryMaxAttempts,
+		baseDelay:   cfg.ViaCEPRetryBaseDelay,
+		timeout:     cfg.ViaCEPTimeout,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "API request failed")
+		return Address{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to read response")
+		return Address{}, err
+	}
+
+	if !utf8.Valid(body) {
+		setAttributes(span, attribute.Bool("response.invalid_utf8", true))
+		body, err = sanitizeUTF8(body, cfg.ViaCEPInvalidUTF8)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Invalid UTF-8 in response")
+			return Address{}, err
+		}
+	}
+
+	var viaCEPResp ViaCEPResponse
+	if err := json.Unmarshal(body, &viaCEPResp); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode response")
+		return Address{}, err
+	}
+
+	// O ViaCEP responde 200 com {"erro": true} para CEPs inexistentes
+	if viaCEPResp.Localidade == "" {
+		span.SetStatus(codes.Error, "city not found")
+		return Address{}, fmt.Errorf("city not found")
+	}
+
+	logUpstreamSuccess(ctx, r.Name(), resp.StatusCode, time.Since(start))
+	return Address{City: viaCEPResp.Localidade, UF: viaCEPResp.UF, Neighborhood: viaCEPResp.Bairro, Provider: r.Name()}, nil
+}
+
+var errInvalidUTF8 = errors.New("response is not valid utf-8")
+
+// sanitizeUTF8 trata um corpo que não é UTF-8 válido conforme
+// VIACEP_INVALID_UTF8: latin1 o reinterpreta como ISO-8859-1 (a causa comum,
+// que preserva os acentos), replace troca os bytes inválidos por U+FFFD e
+// reject devolve errInvalidUTF8
+func sanitizeUTF8(body []byte, mode string) ([]byte, error) {
+	switch mode {
+	case "latin1":
+		runes := make([]rune, len(body))
+		for i, b := range body {
+			runes[i] = rune(b)
+		}
+		return []byte(string(runes)), nil
+	case "replace":
+		return bytes.ToValidUTF8(body, []byte("\uFFFD")), nil
+	}
+	return nil, errInvalidUTF8
+}
+
+type BrasilAPICEPResponse struct {
+	City         string `json:"city"`
+	State        string `json:"state"`
+	Neighborhood string `json:"neighborhood"`
+	Location     struct {
+		Coordinates struct {
+			Latitude  string `json:"latitude"`
+			Longitude string `json:"longitude"`
+		} `json:"coordinates"`
+	} `json:"location"`
+}
+
+type brasilAPIResolver struct{}
+
+func (brasilAPIResolver) Name() string { return "brasilapi" }
+
+func (r brasilAPIResolver) Resolve(ctx context.Context, cep string) (Address, error) {
+	tracer := otel.Tracer("service-b")
+	ctx, span := startPhase(ctx, tracer, "resolve-cep-brasilapi")
+	defer span.End()
+
+	setAttributes(span, attribute.String("api.url", "brasilapi.com.br"))
+
+	start := time.Now()
+	// Sem novas tentativas: a BrasilAPI já é o provedor de reserva
+	resp, err := getCEP(ctx, r.Name(), fmt.Sprintf("%s/api/cep/v2/%s", cfg.BrasilAPIURL, cep), retryPolicy{
+		provider:    r.Name(),
+		maxAttempts: 1,
+		timeout:     cfg.BrasilAPITimeout,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "API request failed")
+		return Address{}, err
+	}
+	defer resp.Body.Close()
+
+	var brasilAPIResp BrasilAPICEPResponse
+	if err := json.NewDecoder(resp.Body).Decode(&brasilAPIResp); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode response")
+		return Address{}, err
+	}
+
+	if brasilAPIResp.City == "" {
+		span.SetStatus(codes.Error, "city not found")
+		return Address{}, fmt.Errorf("city not found")
+	}
+
+	logUpstreamSuccess(ctx, r.Name(), resp.StatusCode, time.Since(start))
+	addr := Address{City: brasilAPIResp.City, UF: brasilAPIResp.State, Neighborhood: brasilAPIResp.Neighborhood, Provider: r.Name()}
+	// Nem todo CEP tem coordenadas na BrasilAPI
+	lat, latErr := strconv.ParseFloat(brasilAPIResp.Location.Coordinates.Latitude, 64)
+	lon, lonErr := strconv.ParseFloat(brasilAPIResp.Location.Coordinates.Longitude, 64)
+	if latErr == nil && lonErr == nil {
+		addr.Coordinates = &Coordinates{Lat: lat, Lon: lon}
+	}
+	return addr, nil
+}
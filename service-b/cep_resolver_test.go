@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// latin1Address é a resposta do ViaCEP codificada em ISO-8859-1, e portanto
+// UTF-8 inválido
+const latin1Address = "{\"cep\": \"01001-000\", \"localidade\": \"S\xe3o Paulo\", \"uf\": \"SP\", \"bairro\": \"S\xe9\"}"
+
+func TestSanitizeUTF8(t *testing.T) {
+	for mode, want := range map[string]string{
+		"latin1":  "São Paulo",
+		"replace": "S�o Paulo",
+	} {
+		got, err := sanitizeUTF8([]byte("S\xe3o Paulo"), mode)
+		if err != nil || string(got) != want {
+			t.Errorf("sanitizeUTF8(%s) = %q, %v, want %q", mode, got, err, want)
+		}
+	}
+	if _, err := sanitizeUTF8([]byte("S\xe3o Paulo"), "reject"); !errors.Is(err, errInvalidUTF8) {
+		t.Errorf("sanitizeUTF8(reject) error = %v, want errInvalidUTF8", err)
+	}
+}
+
+func TestViaCEPInvalidUTF8(t *testing.T) {
+	tests := []struct {
+		mode     string
+		wantCity string
+	}{
+		{"latin1", "São Paulo"},
+		{"replace", "S�o Paulo"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.mode, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, latin1Address)
+			}))
+			t.Cleanup(srv.Close)
+			setupTest(t, "VIACEP_URL", srv.URL, "VIACEP_INVALID_UTF8", tc.mode)
+			spans := recordSpans(t)
+
+			rec := getTemperature(t, "/temperature/01001000")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d (body %s)", rec.Code, rec.Body.String())
+			}
+			if got := decodeBody[TemperatureResponse](t, rec).City; got != tc.wantCity {
+				t.Errorf("city = %q, want %q", got, tc.wantCity)
+			}
+
+			span := findSpan(t, spans.Ended(), "resolve-cep-viacep")
+			if v, _ := spanAttribute(span, "response.invalid_utf8"); !v.AsBool() {
+				t.Error("resolve-cep-viacep span lacks response.invalid_utf8=true")
+			}
+			for _, s := range spans.Ended() {
+				for _, attr := range s.Attributes() {
+					if attr.Value.Type() == attribute.STRING && !utf8.ValidString(attr.Value.AsString()) {
+						t.Errorf("span %s attribute %s is not valid UTF-8", s.Name(), attr.Key)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestViaCEPInvalidUTF8Rejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, latin1Address)
+	}))
+	t.Cleanup(srv.Close)
+	setupTest(t, "VIACEP_URL", srv.URL, "VIACEP_INVALID_UTF8", "reject")
+
+	_, err := viaCEPResolver{}.Resolve(context.Background(), "01001000")
+	if !errors.Is(err, errInvalidUTF8) {
+		t.Errorf("Resolve error = %v, want errInvalidUTF8", err)
+	}
+	assertError(t, getTemperature(t, "/temperature/01001000"), http.StatusInternalServerError, "city_fetch_failed")
+}
+
+func TestSetAttributesSanitizesUTF8(t *testing.T) {
+	setupTest(t)
+	spans := recordSpans(t)
+
+	_, span := otel.Tracer("test").Start(context.Background(), "utf8")
+	setAttributes(span, attribute.String("city", "S\xe3o Paulo"))
+	span.End()
+
+	if v, _ := spanAttribute(spans.Ended()[0], "city"); v.AsString() != "S�o Paulo" {
+		t.Errorf("city = %q, want the invalid byte replaced", v.AsString())
+	}
+}
+
+func TestCEPProviderFallback(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		breakerOpen bool
+	}{
+		{"ViaCEP server error", false},
+		{"ViaCEP breaker open", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var viaCEPCalls atomic.Int32
+			viaCEP := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				viaCEPCalls.Add(1)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			}))
+			t.Cleanup(viaCEP.Close)
+			brasilAPI, brasilAPICalls := newBrasilAPIStub(t)
+			setupTest(t,
+				"CEP_PROVIDERS", "viacep,brasilapi",
+				"VIACEP_URL", viaCEP.URL,
+				"BRASILAPI_URL", brasilAPI.URL,
+				"BREAKER_FAILURE_THRESHOLD", "1",
+			)
+			if tc.breakerOpen {
+				breakers.get("viacep").record(context.Background(), true)
+			}
+
+			sources := getSources(t, "/temperature/01001000?debug=true")
+			if sources == nil || sources.CEP == nil || *sources.CEP != (Source{"brasilapi", false}) {
+				t.Errorf("sources = %+v, want cep from brasilapi", sources)
+			}
+			if n := brasilAPICalls.Load(); n != 1 {
+				t.Errorf("BrasilAPI calls = %d, want 1", n)
+			}
+			if n := viaCEPCalls.Load(); tc.breakerOpen != (n == 0) {
+				t.Errorf("ViaCEP calls = %d with breaker open = %v", n, tc.breakerOpen)
+			}
+		})
+	}
+}
+
+func TestCEPNotFoundSkipsFallback(t *testing.T) {
+	viaCEP, _ := newViaCEPStub(t, map[string]string{})
+	brasilAPI, brasilAPICalls := newBrasilAPIStub(t)
+	setupTest(t,
+		"CEP_PROVIDERS", "viacep,brasilapi",
+		"VIACEP_URL", viaCEP.URL,
+		"BRASILAPI_URL", brasilAPI.URL,
+	)
+
+	// O ViaCEP é definitivo para CEPs inexistentes
+	assertError(t, getTemperature(t, "/temperature/01001000"), http.StatusNotFound, "zipcode_not_found")
+	if n := brasilAPICalls.Load(); n != 0 {
+		t.Errorf("BrasilAPI calls = %d, want 0 after a ViaCEP not found", n)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Preposições e artigos mantidos em minúsculas no modo title-case,
+// como em "São José dos Campos"
+var cityLowercaseWords = map[string]bool{
+	"de": true, "da": true, "do": true, "das": true, "dos": true, "e": true,
+}
+
+// Substituições usadas no modo ascii-fold para as letras acentuadas do português
+var asciiFolder = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "õ", "o", "ö", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "ñ", "n",
+	"Á", "A", "À", "A", "Â", "A", "Ã", "A", "Ä", "A",
+	"É", "E", "È", "E", "Ê", "E", "Ë", "E",
+	"Í", "I", "Ì", "I", "Î", "I", "Ï", "I",
+	"Ó", "O", "Ò", "O", "Ô", "O", "Õ", "O", "Ö", "O",
+	"Ú", "U", "Ù", "U", "Û", "U", "Ü", "U",
+	"Ç", "C", "Ñ", "N",
+)
+
+// canonicalCity aplica ao nome da cidade a forma definida em CITY_NAME_FORM
+func canonicalCity(city, form string) string {
+	switch form {
+	case "title-case":
+		return titleCaseCity(city)
+	case "ascii-fold":
+		return asciiFolder.Replace(city)
+	}
+	return city
+}
+
+func titleCaseCity(city string) string {
+	words := strings.Fields(strings.ToLower(city))
+	for i, word := range words {
+		if i > 0 && cityLowercaseWords[word] {
+			continue
+		}
+		// Contrações como "d'oeste" mantêm o "d'" em minúsculas
+		if rest, ok := strings.CutPrefix(word, "d'"); ok && rest != "" {
+			words[i] = "d'" + capitalizeParts(rest, "-")
+			continue
+		}
+		// Nomes compostos como "embu-guaçu" capitalizam cada parte
+		words[i] = capitalizeParts(word, "-")
+	}
+	return strings.Join(words, " ")
+}
+
+func capitalizeParts(word, sep string) string {
+	parts := strings.Split(word, sep)
+	for i, part := range parts {
+		r, size := utf8.DecodeRuneInString(part)
+		if r != utf8.RuneError {
+			parts[i] = string(unicode.ToUpper(r)) + part[size:]
+		}
+	}
+	return strings.Join(parts, sep)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCanonicalCity(t *testing.T) {
+	tests := []struct {
+		city, asIs, titleCase, asciiFold string
+	}{
+		{"SÃO PAULO", "SÃO PAULO", "São Paulo", "SAO PAULO"},
+		{"são josé dos campos", "são josé dos campos", "São José dos Campos", "sao jose dos campos"},
+		{"Embu-Guaçu", "Embu-Guaçu", "Embu-Guaçu", "Embu-Guacu"},
+		{"santa bárbara d'oeste", "santa bárbara d'oeste", "Santa Bárbara d'Oeste", "santa barbara d'oeste"},
+		{"  Itaúna   do Sul ", "  Itaúna   do Sul ", "Itaúna do Sul", "  Itauna   do Sul "},
+	}
+	for _, tt := range tests {
+		for form, want := range map[string]string{
+			"as-is":      tt.asIs,
+			"title-case": tt.titleCase,
+			"ascii-fold": tt.asciiFold,
+		} {
+			if got := canonicalCity(tt.city, form); got != want {
+				t.Errorf("canonicalCity(%q, %s) = %q, want %q", tt.city, form, got, want)
+			}
+		}
+	}
+}
+
+func TestTemperatureCityNameForm(t *testing.T) {
+	for form, want := range map[string]string{
+		"as-is":      "SÃO JOSÉ DOS CAMPOS",
+		"title-case": "São José dos Campos",
+		"ascii-fold": "SAO JOSE DOS CAMPOS",
+	} {
+		t.Run(form, func(t *testing.T) {
+			srv, _ := newViaCEPStub(t, map[string]string{
+				"12210000": `{"cep": "12210-000", "localidade": "SÃO JOSÉ DOS CAMPOS", "uf": "SP", "bairro": "Centro"}`,
+			})
+			setupTest(t, "VIACEP_URL", srv.URL, "CITY_NAME_FORM", form)
+
+			rec := getTemperature(t, "/temperature/12210000")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d (body %s)", rec.Code, rec.Body.String())
+			}
+			if got := decodeBody[TemperatureResponse](t, rec).City; got != want {
+				t.Errorf("city = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestTemperatureCityBypassesCEPLookup(t *testing.T) {
+	calls := setupWithViaCEP(t)
+
+	rec := postTemperature(t, "/temperature", `{"city": "Campinas"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (body %s)", rec.Code, rec.Body.String())
+	}
+	if got := decodeBody[TemperatureResponse](t, rec).City; got != "Campinas" {
+		t.Errorf("city = %q, want Campinas", got)
+	}
+	if n := calls.Load(); n != 0 {
+		t.Errorf("ViaCEP calls = %d, want 0 when the city is informed", n)
+	}
+
+	if rec := postTemperature(t, "/temperature", `{"cep": "01001000"}`); rec.Code != http.StatusOK {
+		t.Fatalf("CEP only: status = %d (body %s)", rec.Code, rec.Body.String())
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("ViaCEP calls = %d, want 1 for a CEP-only request", n)
+	}
+}
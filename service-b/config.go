@@ -0,0 +1,278 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config centraliza as configurações do serviço. Cada campo é lido da
+// variável de ambiente indicada na tag `env`, usando `default` quando ela
+// não está definida, e verificado pelas regras da tag `validate`
+// (required, min=N, max=N, oneof=a b c).
+type Config struct {
+	Port                    string   `env:"PORT" default:"8081" validate:"required"`
+	MetricsAddr             string   `env:"METRICS_ADDR"`
+	HealthStatusCode        int      `env:"HEALTH_STATUS_CODE" default:"200" validate:"oneof=200 204"`
+	ZipkinEndpoint          string   `env:"OTEL_EXPORTER_ZIPKIN_ENDPOINT"`
+	TraceVerbosity          string   `env:"TRACE_VERBOSITY" default:"full" validate:"oneof=full minimal"`
+	TraceExporters          []string `env:"TRACE_EXPORTERS" default:"zipkin" validate:"required,oneof=zipkin otlp stdout"`
+	OTLPProtocol            string   `env:"OTEL_EXPORTER_OTLP_PROTOCOL" default:"grpc" validate:"oneof=grpc http/protobuf"`
+	TraceAttributeMaxLength int      `env:"TRACE_ATTRIBUTE_MAX_LENGTH" default:"256" validate:"min=1"`
+	MinimalResponse         bool     `env:"MINIMAL_RESPONSE" default:"false"`
+
+	SlowRequestThresholdMS int    `env:"SLOW_REQUEST_THRESHOLD_MS" default:"2000" validate:"min=0"`
+	LogLevel               string `env:"LOG_LEVEL" default:"info" validate:"oneof=debug info warn error"`
+
+	DeployEnv           string `env:"DEPLOY_ENV" default:"development" validate:"required"`
+	DeployRegion        string `env:"DEPLOY_REGION"`
+	DeployZone          string `env:"DEPLOY_ZONE"`
+	TracingProfilesFile string `env:"TRACING_PROFILES_FILE"`
+	TracesSamplerArg    string `env:"OTEL_TRACES_SAMPLER_ARG"`
+
+	ValidateContentLength      bool          `env:"VALIDATE_CONTENT_LENGTH" default:"false"`
+	MaxBodyBytes               int64         `env:"MAX_BODY_BYTES" default:"1048576" validate:"min=1"`
+	UpstreamDisableKeepAlive   bool          `env:"UPSTREAM_DISABLE_KEEPALIVE" default:"false"`
+	HTTPClientTimeout          time.Duration `env:"HTTP_CLIENT_TIMEOUT" default:"5s" validate:"min=1ms"`
+	RequestTimeout             time.Duration `env:"REQUEST_TIMEOUT" default:"10s" validate:"min=1ms"`
+	ShutdownGracePeriod        time.Duration `env:"SHUTDOWN_GRACE_PERIOD" default:"10s" validate:"min=0s"`
+	PrewarmConnections         bool          `env:"PREWARM_CONNECTIONS" default:"false"`
+	StartupUpstreamCheck       bool          `env:"STARTUP_UPSTREAM_CHECK" default:"false"`
+	StartupCheckCity           string        `env:"STARTUP_CHECK_CITY" default:"São Paulo" validate:"required"`
+	UpstreamMinInterval        time.Duration `env:"UPSTREAM_MIN_INTERVAL" default:"0s" validate:"min=0s"`
+	UpstreamLogSampleRate      float64       `env:"UPSTREAM_LOG_SAMPLE_RATE" default:"1.0" validate:"min=0,max=1"`
+	CEPCacheTTL                time.Duration `env:"CEP_CACHE_TTL" default:"24h" validate:"min=0s"`
+	CEPCacheCleanupInterval    time.Duration `env:"CEP_CACHE_CLEANUP_INTERVAL" default:"10m" validate:"min=1s"`
+	NegativeCacheTTL           time.Duration `env:"NEGATIVE_CACHE_TTL" default:"60s" validate:"min=0s"`
+	CacheShards                int           `env:"CACHE_SHARDS" default:"16" validate:"min=1,max=256"`
+	NearbyMax                  int           `env:"NEARBY_MAX" default:"5" validate:"min=0,max=20"`
+	FuzzyCEP                   bool          `env:"FUZZY_CEP" default:"false"`
+	FuzzyCEPMaxAttempts        int           `env:"FUZZY_CEP_MAX_ATTEMPTS" default:"7" validate:"min=1,max=7"`
+	CEPProviders               []string      `env:"CEP_PROVIDERS" default:"viacep,brasilapi" validate:"required,oneof=viacep brasilapi"`
+	BrasilAPITimeout           time.Duration `env:"BRASILAPI_TIMEOUT" default:"5s" validate:"min=1ms"`
+	ViaCEPTimeout              time.Duration `env:"VIACEP_TIMEOUT" default:"5s" validate:"min=1ms"`
+	ViaCEPRetryMaxAttempts     int           `env:"VIACEP_RETRY_MAX_ATTEMPTS" default:"3" validate:"min=1,max=10"`
+	ViaCEPRetryBaseDelay       time.Duration `env:"VIACEP_RETRY_BASE_DELAY" default:"100ms" validate:"min=0s"`
+	ViaCEPInvalidUTF8          string        `env:"VIACEP_INVALID_UTF8" default:"latin1" validate:"oneof=latin1 replace reject"`
+	WeatherAPITimeout          time.Duration `env:"WEATHERAPI_TIMEOUT" default:"5s" validate:"min=1ms"`
+	WeatherAPIRetryMaxAttempts int           `env:"WEATHERAPI_RETRY_MAX_ATTEMPTS" default:"2" validate:"min=1,max=10"`
+	WeatherAPIRetryBaseDelay   time.Duration `env:"WEATHERAPI_RETRY_BASE_DELAY" default:"100ms" validate:"min=0s"`
+	OpenWeatherMapTimeout      time.Duration `env:"OPENWEATHERMAP_TIMEOUT" default:"5s" validate:"min=1ms"`
+	RetryMaxElapsedMS          int           `env:"RETRY_MAX_ELAPSED_MS" default:"0" validate:"min=0"`
+	BreakerFailureThreshold    int           `env:"BREAKER_FAILURE_THRESHOLD" default:"5" validate:"min=0"`
+	BreakerCooldown            time.Duration `env:"BREAKER_COOLDOWN" default:"30s" validate:"min=0s"`
+	WeatherSkipCities          []string      `env:"WEATHER_SKIP_CITIES"`
+	TimestampFormat            string        `env:"TIMESTAMP_FORMAT" default:"rfc3339" validate:"oneof=rfc3339 epoch"`
+	CityNameForm               string        `env:"CITY_NAME_FORM" default:"as-is" validate:"oneof=as-is title-case ascii-fold"`
+	AuditLog                   bool          `env:"AUDIT_LOG" default:"false"`
+	NoTempAs200                bool          `env:"NO_TEMP_AS_200" default:"false"`
+	CollapseCacheHitSpans      bool          `env:"COLLAPSE_CACHE_HIT_SPANS" default:"false"`
+	ETagEnabled                bool          `env:"ETAG_ENABLED" default:"false"`
+	BatchMaxSize               int           `env:"BATCH_MAX_SIZE" default:"50" validate:"min=1"`
+	BatchConcurrency           int           `env:"BATCH_CONCURRENCY" default:"4" validate:"min=1,max=32"`
+
+	WeatherProvider       string        `env:"WEATHER_PROVIDER" default:"weatherapi" validate:"oneof=weatherapi openweathermap mock"`
+	WeatherAPIKey         string        `env:"WEATHER_API_KEY"`
+	WeatherAPIKeys        []string      `env:"WEATHER_API_KEYS"`
+	WeatherAPIKeyCooldown time.Duration `env:"WEATHER_API_KEY_COOLDOWN" default:"1h" validate:"min=0s"`
+	WeatherAPIURL         string        `env:"WEATHER_API_URL"`
+	ViaCEPURL             string        `env:"VIACEP_URL"`
+	BrasilAPIURL          string        `env:"BRASILAPI_URL"`
+	OpenWeatherMapAPIKey  string        `env:"OPENWEATHERMAP_API_KEY"`
+	OpenWeatherMapURL     string        `env:"OPENWEATHERMAP_URL"`
+}
+
+var cfg Config
+
+func loadConfig() (Config, error) {
+	var c Config
+	if err := bindEnv(&c); err != nil {
+		return Config{}, err
+	}
+	if c.WeatherAPIURL == "" {
+		c.WeatherAPIURL = weatherAPIURL
+	}
+	if c.ViaCEPURL == "" {
+		c.ViaCEPURL = viaCEPBaseURL
+	}
+	if c.BrasilAPIURL == "" {
+		c.BrasilAPIURL = brasilAPIBaseURL
+	}
+	if c.OpenWeatherMapURL == "" {
+		c.OpenWeatherMapURL = openWeatherMapURL
+	}
+	// Só o throttle guarda o clima: sem UPSTREAM_MIN_INTERVAL nenhuma
+	// requisição seria atendida inteiramente do cache
+	if c.CollapseCacheHitSpans && c.UpstreamMinInterval <= 0 {
+		return Config{}, errors.New("COLLAPSE_CACHE_HIT_SPANS: requires UPSTREAM_MIN_INTERVAL greater than 0s")
+	}
+	return c, nil
+}
+
+// bindEnv preenche os campos de target (ponteiro para struct) a partir das
+// variáveis de ambiente e acumula todos os erros de conversão e validação
+func bindEnv(target any) error {
+	v := reflect.ValueOf(target).Elem()
+	t := v.Type()
+
+	var errs []error
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("env")
+		if name == "" {
+			continue
+		}
+
+		raw := os.Getenv(name)
+		if raw == "" {
+			raw = field.Tag.Get("default")
+		}
+
+		if err := setField(v.Field(i), raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		if err := validateField(v.Field(i), field.Tag.Get("validate")); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func setField(f reflect.Value, raw string) error {
+	if f.Type() == reflect.TypeOf(time.Duration(0)) {
+		if raw == "" {
+			return nil
+		}
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		f.SetInt(int64(d))
+		return nil
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(raw)
+	case reflect.Bool:
+		if raw == "" {
+			return nil
+		}
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		if raw == "" {
+			return nil
+		}
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		f.SetInt(n)
+	case reflect.Float64:
+		if raw == "" {
+			return nil
+		}
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		f.SetFloat(n)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		f.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported field type %s", f.Type())
+	}
+	return nil
+}
+
+func validateField(f reflect.Value, rules string) error {
+	if rules == "" {
+		return nil
+	}
+
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			if f.IsZero() || (f.Kind() == reflect.Slice && f.Len() == 0) {
+				return fmt.Errorf("value is required")
+			}
+		case "oneof":
+			// Em listas, a regra vale para cada item
+			values := []string{fmt.Sprint(f.Interface())}
+			if f.Kind() == reflect.Slice {
+				values = f.Interface().([]string)
+			}
+			options := strings.Fields(arg)
+			for _, value := range values {
+				if !contains(options, value) {
+					return fmt.Errorf("value %q must be one of %s", value, strings.Join(options, ", "))
+				}
+			}
+		case "min", "max":
+			limit, value, err := numericBound(f, arg)
+			if err != nil {
+				return err
+			}
+			if name == "min" && value < limit {
+				return fmt.Errorf("value must be >= %s", arg)
+			}
+			if name == "max" && value > limit {
+				return fmt.Errorf("value must be <= %s", arg)
+			}
+		default:
+			return fmt.Errorf("unknown validation rule %q", name)
+		}
+	}
+	return nil
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// numericBound converte o limite da regra e o valor do campo para float64,
+// tratando durações no formato do time.ParseDuration
+func numericBound(f reflect.Value, arg string) (limit, value float64, err error) {
+	if f.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(arg)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid duration bound %q", arg)
+		}
+		return float64(d), float64(f.Int()), nil
+	}
+
+	limit, err = strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid numeric bound %q", arg)
+	}
+	switch f.Kind() {
+	case reflect.Int, reflect.Int64:
+		return limit, float64(f.Int()), nil
+	case reflect.Float64:
+		return limit, f.Float(), nil
+	case reflect.String, reflect.Slice:
+		return limit, float64(f.Len()), nil
+	}
+	return 0, 0, fmt.Errorf("min/max not supported for %s", f.Type())
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfigDefaults(t *testing.T) {
+	c, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	if c.Port != "8081" || c.WeatherProvider != "weatherapi" || c.TimestampFormat != "rfc3339" {
+		t.Errorf("Port/WeatherProvider/TimestampFormat = %q/%q/%q, want the defaults", c.Port, c.WeatherProvider, c.TimestampFormat)
+	}
+	if c.CEPCacheTTL != 24*time.Hour || c.UpstreamLogSampleRate != 1 || c.CacheShards != 16 {
+		t.Errorf("CEPCacheTTL/UpstreamLogSampleRate/CacheShards = %v/%v/%d, want 24h/1/16",
+			c.CEPCacheTTL, c.UpstreamLogSampleRate, c.CacheShards)
+	}
+	if !reflect.DeepEqual(c.CEPProviders, []string{"viacep", "brasilapi"}) {
+		t.Errorf("CEPProviders = %v, want [viacep brasilapi]", c.CEPProviders)
+	}
+	// URLs sem tag default vêm das constantes dos provedores
+	if c.WeatherAPIURL != weatherAPIURL || c.ViaCEPURL != viaCEPBaseURL ||
+		c.BrasilAPIURL != brasilAPIBaseURL || c.OpenWeatherMapURL != openWeatherMapURL {
+		t.Errorf("provider URLs = %q, %q, %q, %q, want the built-in endpoints",
+			c.WeatherAPIURL, c.ViaCEPURL, c.BrasilAPIURL, c.OpenWeatherMapURL)
+	}
+}
+
+func TestLoadConfigOverrides(t *testing.T) {
+	t.Setenv("PORT", "9091")
+	t.Setenv("CEP_CACHE_TTL", "90m")
+	t.Setenv("UPSTREAM_LOG_SAMPLE_RATE", "0.1")
+	t.Setenv("FUZZY_CEP", "true")
+	t.Setenv("CEP_PROVIDERS", "brasilapi")
+	t.Setenv("WEATHER_API_URL", "http://127.0.0.1:9999/v1/current.json")
+
+	c, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	if c.Port != "9091" || c.CEPCacheTTL != 90*time.Minute || c.UpstreamLogSampleRate != 0.1 || !c.FuzzyCEP {
+		t.Errorf("config = %+v, want the overridden values", c)
+	}
+	if !reflect.DeepEqual(c.CEPProviders, []string{"brasilapi"}) {
+		t.Errorf("CEPProviders = %v, want [brasilapi]", c.CEPProviders)
+	}
+	if c.WeatherAPIURL != "http://127.0.0.1:9999/v1/current.json" {
+		t.Errorf("WeatherAPIURL = %q, want the override", c.WeatherAPIURL)
+	}
+}
+
+func TestLoadConfigValidation(t *testing.T) {
+	tests := []struct {
+		env, value, want string
+	}{
+		{"WEATHER_PROVIDER", "accuweather", "WEATHER_PROVIDER: value \"accuweather\" must be one of weatherapi, openweathermap, mock"},
+		{"CEP_PROVIDERS", "viacep,correios", "CEP_PROVIDERS: value \"correios\" must be one of viacep, brasilapi"},
+		{"UPSTREAM_LOG_SAMPLE_RATE", "1.5", "UPSTREAM_LOG_SAMPLE_RATE: value must be <= 1"},
+		{"CACHE_SHARDS", "0", "CACHE_SHARDS: value must be >= 1"},
+		{"NEARBY_MAX", "21", "NEARBY_MAX: value must be <= 20"},
+		{"WEATHERAPI_TIMEOUT", "0s", "WEATHERAPI_TIMEOUT: value must be >= 1ms"},
+		{"CEP_CACHE_TTL", "1 day", "CEP_CACHE_TTL: invalid duration \"1 day\""},
+		{"BATCH_MAX_SIZE", "many", "BATCH_MAX_SIZE: invalid integer \"many\""},
+		{"UPSTREAM_LOG_SAMPLE_RATE", "half", "UPSTREAM_LOG_SAMPLE_RATE: invalid number \"half\""},
+		{"AUDIT_LOG", "yes", "AUDIT_LOG: invalid boolean \"yes\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env+"="+tt.value, func(t *testing.T) {
+			t.Setenv(tt.env, tt.value)
+			if _, err := loadConfig(); err == nil || err.Error() != tt.want {
+				t.Errorf("loadConfig error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigReportsAllErrors(t *testing.T) {
+	t.Setenv("CACHE_SHARDS", "512")
+	t.Setenv("TIMESTAMP_FORMAT", "iso")
+
+	_, err := loadConfig()
+	if err == nil {
+		t.Fatal("loadConfig succeeded, want an error")
+	}
+	for _, name := range []string{"CACHE_SHARDS", "TIMESTAMP_FORMAT"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error %q does not mention %s", err, name)
+		}
+	}
+}
+
+func TestLoadConfigCollapseCacheHitSpansRequiresThrottle(t *testing.T) {
+	t.Setenv("COLLAPSE_CACHE_HIT_SPANS", "true")
+	t.Setenv("UPSTREAM_MIN_INTERVAL", "0s")
+	if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), "UPSTREAM_MIN_INTERVAL") {
+		t.Fatalf("loadConfig error = %v, want COLLAPSE_CACHE_HIT_SPANS rejected without UPSTREAM_MIN_INTERVAL", err)
+	}
+
+	t.Setenv("UPSTREAM_MIN_INTERVAL", "2s")
+	if _, err := loadConfig(); err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+}
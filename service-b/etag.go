@@ -0,0 +1,40 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// observationETag deriva o ETag da versão da observação meteorológica (o
+// last_updated da WeatherAPI) e da cidade resolvida, para que respostas com a
+// mesma observação tenham o mesmo ETag entre requisições. Os parâmetros de
+// consulta e o Accept entram no cálculo porque mudam a representação. É fraco
+// (W/) porque o corpo pode variar em detalhes que não mudam a observação.
+func observationETag(r *http.Request, city string, lastUpdated time.Time) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%d\n%s\n%s", city, lastUpdated.Unix(), r.URL.RawQuery, r.Header.Get("Accept"))
+	return `W/"` + hex.EncodeToString(h.Sum(nil))[:16] + `"`
+}
+
+// etagMatches indica se o If-None-Match da requisição contém etag; a
+// comparação é fraca, como a RFC 9110 determina para esse cabeçalho
+func etagMatches(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// setupWithObservation sobe a WeatherAPI falsa com o last_updated_epoch
+// controlado pelo teste, para simular uma nova observação
+func setupWithObservation(t *testing.T, env ...string) *atomic.Int64 {
+	t.Helper()
+	current, err := os.ReadFile("testdata/weatherapi_current.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lastUpdated atomic.Int64
+	lastUpdated.Store(1760540100)
+	setupWithWeatherAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		body := bytes.Replace(current, []byte(`"last_updated_epoch":1760540100`),
+			[]byte(`"last_updated_epoch":`+strconv.FormatInt(lastUpdated.Load(), 10)), 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}, append([]string{"ETAG_ENABLED", "true"}, env...)...)
+	return &lastUpdated
+}
+
+func getWithETag(t *testing.T, target, etag string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	if etag != "" {
+		r.Header.Set("If-None-Match", etag)
+	}
+	return serve(r)
+}
+
+func TestETagStableForSameObservation(t *testing.T) {
+	setupWithObservation(t)
+
+	first := getWithETag(t, "/temperature/01001000", "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("status = %d, ETag = %q, want 200 with an ETag", first.Code, etag)
+	}
+	if second := getWithETag(t, "/temperature/01001000", ""); second.Header().Get("ETag") != etag {
+		t.Errorf("ETag changed from %s to %s for the same observation", etag, second.Header().Get("ETag"))
+	}
+
+	rec := getWithETag(t, "/temperature/01001000", etag)
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("status = %d, want 304", rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("304 with body %q", rec.Body.String())
+	}
+}
+
+func TestETagChangesWithNewObservation(t *testing.T) {
+	lastUpdated := setupWithObservation(t)
+
+	etag := getWithETag(t, "/temperature/01001000", "").Header().Get("ETag")
+	lastUpdated.Add(900)
+
+	rec := getWithETag(t, "/temperature/01001000", etag)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 after a new observation", rec.Code)
+	}
+	if got := rec.Header().Get("ETag"); got == "" || got == etag {
+		t.Errorf("ETag = %q, want a new value (previous %s)", got, etag)
+	}
+}
+
+func TestBatchIgnoresConditionalHeaders(t *testing.T) {
+	setupWithObservation(t)
+
+	etag := getWithETag(t, "/temperature/01001000", "").Header().Get("ETag")
+	r := httptest.NewRequest(http.MethodPost, "/temperature/batch", strings.NewReader(`{"ceps": ["01001000"]}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("If-None-Match", etag)
+	r.Header.Set("If-Modified-Since", "Wed, 15 Oct 2025 15:00:00 GMT")
+	rec := serve(r)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+	}
+
+	got := decodeBody[BatchResponse](t, rec)
+	if len(got.Results) != 1 {
+		t.Fatalf("results = %+v, want 1 item", got.Results)
+	}
+	item := got.Results[0]
+	if item.Status != http.StatusOK || item.Error != nil || len(item.Result) == 0 {
+		t.Errorf("item = status %d, error %+v, result %s; want 200 with the full result", item.Status, item.Error, item.Result)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// isCEPNotFound indica se o erro de fetchCityFromCEP significa que o CEP não
+// existe, e não uma falha ao consultar o provedor
+func isCEPNotFound(err error) bool {
+	return err.Error() == "city not found" || err.Error() == "can not find zipcode"
+}
+
+// fuzzyCandidates gera variações do CEP com dígitos adjacentes trocados, o
+// erro de digitação mais comum
+func fuzzyCandidates(cep string) []string {
+	seen := map[string]bool{cep: true}
+	var candidates []string
+	for i := 0; i+1 < len(cep); i++ {
+		c := []byte(cep)
+		c[i], c[i+1] = c[i+1], c[i]
+		if !seen[string(c)] {
+			seen[string(c)] = true
+			candidates = append(candidates, string(c))
+		}
+	}
+	return candidates
+}
+
+// fuzzyResolveCEP tenta resolver variações de um CEP não encontrado, limitado
+// a FUZZY_CEP_MAX_ATTEMPTS consultas, e devolve a primeira que existir
+func fuzzyResolveCEP(ctx context.Context, cep string) (addr Address, corrected string, err error) {
+	tracer := otel.Tracer("service-b")
+	ctx, span := startPhase(ctx, tracer, "fuzzy-resolve-cep")
+	defer span.End()
+
+	candidates := fuzzyCandidates(cep)
+	if len(candidates) > cfg.FuzzyCEPMaxAttempts {
+		candidates = candidates[:cfg.FuzzyCEPMaxAttempts]
+	}
+
+	for i, candidate := range candidates {
+		addr, err := fetchCityFromCEP(ctx, candidate)
+		if err == nil {
+			setAttributes(span,
+				attribute.String("cep.corrected", candidate),
+				attribute.Int("fuzzy.attempts", i+1),
+			)
+			slog.InfoContext(ctx, "fuzzy match", "resolved_cep", candidate)
+			return addr, candidate, nil
+		}
+		if !isCEPNotFound(err) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Fuzzy lookup failed")
+			return Address{}, "", err
+		}
+	}
+
+	setAttributes(span, attribute.Int("fuzzy.attempts", len(candidates)))
+	return Address{}, "", fmt.Errorf("city not found")
+}
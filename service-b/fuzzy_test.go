@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestFuzzyCandidates(t *testing.T) {
+	got := fuzzyCandidates("10001000")
+	// Trocas de dígitos iguais repetem o CEP original e são descartadas
+	want := []string{"01001000", "10010000", "10000100"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fuzzyCandidates = %v, want %v", got, want)
+	}
+	if got := fuzzyCandidates("12345678"); len(got) != 7 {
+		t.Errorf("fuzzyCandidates(12345678) = %v, want the 7 adjacent swaps", got)
+	}
+}
+
+func TestTemperatureFuzzyUncorrectable(t *testing.T) {
+	calls := setupWithViaCEP(t, "FUZZY_CEP", "true", "FUZZY_CEP_MAX_ATTEMPTS", "3")
+
+	// Nenhuma troca de 98765432 existe no ViaCEP falso
+	rec := getTemperature(t, "/temperature/98765432")
+	assertError(t, rec, http.StatusNotFound, "zipcode_not_found")
+	// A consulta original e no máximo FUZZY_CEP_MAX_ATTEMPTS variações
+	if got := calls.Load(); got != 4 {
+		t.Errorf("ViaCEP calls = %d, want 4", got)
+	}
+}
+
+func TestTemperatureFuzzyDisabled(t *testing.T) {
+	calls := setupWithViaCEP(t)
+
+	assertError(t, getTemperature(t, "/temperature/10001000"), http.StatusNotFound, "zipcode_not_found")
+	if got := calls.Load(); got != 1 {
+		t.Errorf("ViaCEP calls = %d, want 1 without FUZZY_CEP", got)
+	}
+}
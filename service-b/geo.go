@@ -0,0 +1,23 @@
+package main
+
+import "math"
+
+// Raio médio da Terra, em km
+const earthRadiusKm = 6371.0
+
+type Coordinates struct {
+	Lat float64
+	Lon float64
+}
+
+// haversineKm calcula a distância em km entre dois pontos pela fórmula de
+// Haversine
+func haversineKm(a, b Coordinates) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(b.Lat - a.Lat)
+	dLon := toRad(b.Lon - a.Lon)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(a.Lat))*math.Cos(toRad(b.Lat))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
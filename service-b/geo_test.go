@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+// newBrasilAPIStub sobe uma BrasilAPI falsa que resolve qualquer CEP para a
+// Praça da Sé, com coordenadas. Devolve o servidor e o número de consultas
+// recebidas.
+func newBrasilAPIStub(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"cep": "01001000", "state": "SP", "city": "São Paulo", "neighborhood": "Sé",
+			"location": {"coordinates": {"latitude": "-23.5505", "longitude": "-46.6333"}}}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestHaversineKm(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Coordinates
+		want float64
+	}{
+		{"same point", Coordinates{-23.5505, -46.6333}, Coordinates{-23.5505, -46.6333}, 0},
+		{"one degree of latitude", Coordinates{0, 0}, Coordinates{1, 0}, 111.19},
+		{"São Paulo to Rio de Janeiro", Coordinates{-23.5505, -46.6333}, Coordinates{-22.9068, -43.1729}, 360.75},
+		{"antipodes", Coordinates{0, 0}, Coordinates{0, 180}, 20015.09},
+	}
+	for _, tt := range tests {
+		got := haversineKm(tt.a, tt.b)
+		if math.Abs(got-tt.want) > 0.01 {
+			t.Errorf("%s: haversineKm = %.2f, want %.2f", tt.name, got, tt.want)
+		}
+		if back := haversineKm(tt.b, tt.a); math.Abs(back-got) > 1e-9 {
+			t.Errorf("%s: haversineKm is not symmetric (%v vs %v)", tt.name, got, back)
+		}
+	}
+}
+
+func TestTemperatureStationDistance(t *testing.T) {
+	// A estação do fixture da WeatherAPI fica em -23.5333, -46.6167
+	brasilAPI, _ := newBrasilAPIStub(t)
+	setupWithWeatherAPI(t, nil, "CEP_PROVIDERS", "brasilapi", "BRASILAPI_URL", brasilAPI.URL)
+
+	rec := getTemperature(t, "/temperature/01001000?extra=true")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (body %s)", rec.Code, rec.Body.String())
+	}
+	if got := decodeBody[map[string]any](t, rec)["station_distance_km"]; got != 2.6 {
+		t.Errorf("station_distance_km = %v, want 2.6", got)
+	}
+
+	rec = getTemperature(t, "/temperature/01001000")
+	if _, ok := decodeBody[map[string]any](t, rec)["station_distance_km"]; ok {
+		t.Error("station_distance_km present without extra=true")
+	}
+}
+
+func TestTemperatureStationDistanceWithoutCoordinates(t *testing.T) {
+	setupWithViaCEP(t)
+
+	rec := getTemperature(t, "/temperature/01001000?extra=true")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (body %s)", rec.Code, rec.Body.String())
+	}
+	body := decodeBody[struct {
+		StationDistanceKm *float64 `json:"station_distance_km"`
+		Warnings          []string `json:"warnings"`
+	}](t, rec)
+	if body.StationDistanceKm != nil {
+		t.Errorf("station_distance_km = %v, want it omitted without CEP coordinates", *body.StationDistanceKm)
+	}
+	if !slices.Contains(body.Warnings, "station_distance_km unavailable") {
+		t.Errorf("warnings = %v, want station_distance_km unavailable", body.Warnings)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const geoJSONContentType = "application/geo+json"
+
+// GeoJSONFeature é a resposta no formato GeoJSON (RFC 7946): o ponto da
+// localidade e, nas propriedades, o mesmo corpo da resposta JSON
+type GeoJSONFeature struct {
+	Type       string        `json:"type"`
+	Geometry   *GeoJSONPoint `json:"geometry"`
+	Properties any           `json:"properties"`
+}
+
+type GeoJSONPoint struct {
+	Type string `json:"type"`
+	// Longitude antes da latitude, como exige a especificação
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// wantsGeoJSON indica se o cliente pediu application/geo+json no Accept
+func wantsGeoJSON(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err == nil && mediaType == geoJSONContentType {
+			return true
+		}
+	}
+	return false
+}
+
+// newGeoJSONFeature monta a Feature com as propriedades informadas. Sem
+// coordenadas a geometria fica nula, o que a especificação permite.
+func newGeoJSONFeature(coords *Coordinates, properties any) GeoJSONFeature {
+	feature := GeoJSONFeature{Type: "Feature", Properties: properties}
+	if coords != nil {
+		feature.Geometry = &GeoJSONPoint{
+			Type:        "Point",
+			Coordinates: [2]float64{coords.Lon, coords.Lat},
+		}
+	}
+	return feature
+}
+
+// featureCoordinates escolhe o ponto da resposta: as coordenadas do CEP quando
+// o provedor as informa, senão as da localidade associada pela WeatherAPI
+func featureCoordinates(addr Address, weather Weather) *Coordinates {
+	if addr.Coordinates != nil {
+		return addr.Coordinates
+	}
+	if weather.Station != (Coordinates{}) {
+		station := weather.Station
+		return &station
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWantsGeoJSON(t *testing.T) {
+	for accept, want := range map[string]bool{
+		"":                     false,
+		"application/json":     false,
+		"application/geo+json": true,
+		"application/json, application/geo+json;q=0.9": true,
+		"application/geo+json; charset=utf-8":          true,
+		"application/geo+jsonx":                        false,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/temperature/01001000", nil)
+		r.Header.Set("Accept", accept)
+		if got := wantsGeoJSON(r); got != want {
+			t.Errorf("wantsGeoJSON(Accept: %q) = %v, want %v", accept, got, want)
+		}
+	}
+}
+
+type geoJSONResponse struct {
+	Type     string `json:"type"`
+	Geometry *struct {
+		Type        string    `json:"type"`
+		Coordinates []float64 `json:"coordinates"`
+	} `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+func getGeoJSON(t *testing.T, target string) geoJSONResponse {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	r.Header.Set("Accept", "application/geo+json")
+	rec := serve(r)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (body %s)", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != geoJSONContentType {
+		t.Errorf("Content-Type = %q, want %s", ct, geoJSONContentType)
+	}
+	return decodeBody[geoJSONResponse](t, rec)
+}
+
+func TestTemperatureGeoJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T)
+		want  []float64
+	}{
+		{"CEP coordinates", func(t *testing.T) {
+			brasilAPI, _ := newBrasilAPIStub(t)
+			setupWithWeatherAPI(t, nil, "CEP_PROVIDERS", "brasilapi", "BRASILAPI_URL", brasilAPI.URL)
+		}, []float64{-46.6333, -23.5505}},
+		// ViaCEP não informa coordenadas: vale a localidade da WeatherAPI
+		{"weather station", func(t *testing.T) { setupWithWeatherAPI(t, nil) }, []float64{-46.6167, -23.5333}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(t)
+
+			feature := getGeoJSON(t, "/temperature/01001000")
+			if feature.Type != "Feature" {
+				t.Errorf("type = %q, want Feature", feature.Type)
+			}
+			if feature.Geometry == nil || feature.Geometry.Type != "Point" {
+				t.Fatalf("geometry = %+v, want a Point", feature.Geometry)
+			}
+			// RFC 7946: longitude antes da latitude
+			if got := feature.Geometry.Coordinates; len(got) != 2 || got[0] != tc.want[0] || got[1] != tc.want[1] {
+				t.Errorf("coordinates = %v, want %v", got, tc.want)
+			}
+			if feature.Properties["city"] != "São Paulo" || feature.Properties["temp_C"] != 22.1 {
+				t.Errorf("properties = %v, want São Paulo at 22.1°C", feature.Properties)
+			}
+		})
+	}
+}
+
+func TestTemperatureGeoJSONWithoutCoordinates(t *testing.T) {
+	setupWithViaCEP(t)
+
+	feature := getGeoJSON(t, "/temperature/01001000")
+	if feature.Type != "Feature" || feature.Geometry != nil {
+		t.Errorf("feature = %+v, want a Feature with null geometry", feature)
+	}
+	if feature.Properties["city"] != "São Paulo" {
+		t.Errorf("properties = %v, want São Paulo", feature.Properties)
+	}
+}
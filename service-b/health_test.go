@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthStatusCode(t *testing.T) {
+	for _, tc := range []struct {
+		env  string
+		want int
+	}{
+		{"", http.StatusOK},
+		{"200", http.StatusOK},
+		{"204", http.StatusNoContent},
+	} {
+		t.Run("HEALTH_STATUS_CODE="+tc.env, func(t *testing.T) {
+			setupTest(t, "HEALTH_STATUS_CODE", tc.env)
+
+			rec := serve(httptest.NewRequest(http.MethodGet, "/health", nil))
+			if rec.Code != tc.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tc.want)
+			}
+			if tc.want == http.StatusNoContent {
+				if rec.Body.Len() != 0 {
+					t.Errorf("body = %q, want none with 204", rec.Body.String())
+				}
+				return
+			}
+			if got := decodeBody[HealthResponse](t, rec); got != (HealthResponse{SchemaVersion: schemaVersion, Status: "ok"}) {
+				t.Errorf("body = %+v", got)
+			}
+		})
+	}
+}
+
+func TestHealthStatusCodeRejectsOtherValues(t *testing.T) {
+	t.Setenv("HEALTH_STATUS_CODE", "503")
+	if _, err := loadConfig(); err == nil {
+		t.Error("loadConfig accepted HEALTH_STATUS_CODE=503")
+	}
+}
+
+func TestReady(t *testing.T) {
+	setupTest(t, "BREAKER_FAILURE_THRESHOLD", "1")
+
+	rec := serve(httptest.NewRequest(http.MethodGet, "/ready", nil))
+	got := decodeBody[ReadinessResponse](t, rec)
+	if rec.Code != http.StatusOK || got.Status != "ready" || got.SchemaVersion != schemaVersion {
+		t.Fatalf("status = %d, body = %+v, want 200 ready with schema_version", rec.Code, got)
+	}
+
+	// Circuito do provedor de clima aberto: não está pronto
+	breakers.get(weatherProvider.Name()).record(context.Background(), true)
+	rec = serve(httptest.NewRequest(http.MethodGet, "/ready", nil))
+	got = decodeBody[ReadinessResponse](t, rec)
+	if rec.Code != http.StatusServiceUnavailable || got.Status != "unavailable" || got.SchemaVersion != schemaVersion {
+		t.Errorf("status = %d, body = %+v, want 503 unavailable with schema_version", rec.Code, got)
+	}
+	if got.Breakers[weatherProvider.Name()] != breakerOpen {
+		t.Errorf("breakers = %v, want %s open", got.Breakers, weatherProvider.Name())
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type ctxKey int
+
+const (
+	ctxKeyCEP ctxKey = iota
+	ctxKeyCity
+)
+
+// withCEP e withCity guardam no contexto o CEP e a cidade da requisição, que
+// traceHandler acrescenta a cada linha de log registrada com esse contexto
+func withCEP(ctx context.Context, cep string) context.Context {
+	return context.WithValue(ctx, ctxKeyCEP, cep)
+}
+
+func withCity(ctx context.Context, city string) context.Context {
+	return context.WithValue(ctx, ctxKeyCity, city)
+}
+
+// logf registra um aviso com a mensagem formatada
+func logf(ctx context.Context, format string, args ...any) {
+	slog.WarnContext(ctx, fmt.Sprintf(format, args...))
+}
+
+// logUpstreamSuccess registra uma chamada bem-sucedida a um provedor externo,
+// amostrada por UPSTREAM_LOG_SAMPLE_RATE. Falhas não passam por aqui e são
+// sempre registradas.
+func logUpstreamSuccess(ctx context.Context, provider string, status int, elapsed time.Duration) {
+	if rand.Float64() >= cfg.UpstreamLogSampleRate {
+		return
+	}
+	slog.InfoContext(ctx, "upstream call succeeded",
+		"provider", provider,
+		"status", status,
+		"duration_ms", elapsed.Milliseconds(),
+	)
+}
+
+// initLogger configura o slog padrão com saída JSON no nível de LOG_LEVEL.
+// Mensagens do pacote log também passam a sair por ele.
+func initLogger(level string) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return err
+	}
+	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: l})
+	slog.SetDefault(slog.New(traceHandler{handler}))
+	return nil
+}
+
+// fatal registra o erro e encerra o processo, como log.Fatal
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// traceHandler acrescenta trace_id e span_id do span do contexto a cada
+// registro, para que logs e traces possam ser cruzados, e o CEP e a cidade da
+// requisição, para que cada linha seja autoexplicativa
+type traceHandler struct {
+	slog.Handler
+}
+
+func (h traceHandler) Handle(ctx context.Context, r slog.Record) error {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	if cep, ok := ctx.Value(ctxKeyCEP).(string); ok {
+		r.AddAttrs(slog.String("cep", cep))
+	}
+	if city, ok := ctx.Value(ctxKeyCity).(string); ok {
+		r.AddAttrs(slog.String("city", city))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h traceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return traceHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h traceHandler) WithGroup(name string) slog.Handler {
+	return traceHandler{h.Handler.WithGroup(name)}
+}
+
+// requestSpan guarda o span raiz aberto pelo handler, que logRequests só
+// conhece depois que o handler retorna
+type requestSpan struct {
+	mu sync.Mutex
+	sc trace.SpanContext
+}
+
+type requestSpanCtxKey struct{}
+
+// recordRequestSpan associa o span raiz da requisição ao log de acesso
+func recordRequestSpan(ctx context.Context, span trace.Span) {
+	if rs, ok := ctx.Value(requestSpanCtxKey{}).(*requestSpan); ok {
+		rs.mu.Lock()
+		defer rs.mu.Unlock()
+		rs.sc = span.SpanContext()
+	}
+}
+
+// logRequests registra cada requisição com método, caminho, status, latência
+// e o trace do span raiz; sondas de saúde e métricas vão para o nível debug
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rs := &requestSpan{}
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), requestSpanCtxKey{}, rs)))
+
+		level := slog.LevelInfo
+		if r.URL.Path == "/health" || r.URL.Path == "/ready" || r.URL.Path == "/metrics" {
+			level = slog.LevelDebug
+		}
+		rs.mu.Lock()
+		ctx := trace.ContextWithSpanContext(r.Context(), rs.sc)
+		rs.mu.Unlock()
+		slog.Log(ctx, level, "request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"latency_ms", time.Since(start).Milliseconds(),
+		)
+	})
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// logEntry é uma linha de log JSON decodificada
+type logEntry map[string]any
+
+// captureLogs redireciona o slog padrão para um buffer durante o teste e
+// devolve uma função que lista as linhas registradas
+func captureLogs(t *testing.T) func() []logEntry {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(traceHandler{slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})}))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	return func() []logEntry {
+		var entries []logEntry
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var entry logEntry
+			if err := json.Unmarshal([]byte(line), &entry); err == nil {
+				entries = append(entries, entry)
+			}
+		}
+		return entries
+	}
+}
+
+// findLogs devolve as linhas cuja mensagem começa com prefix
+func findLogs(entries []logEntry, prefix string) []logEntry {
+	var found []logEntry
+	for _, e := range entries {
+		if msg, _ := e["msg"].(string); strings.HasPrefix(msg, prefix) {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+func TestUpstreamSuccessLogSampling(t *testing.T) {
+	const calls = 2000
+	tests := []struct {
+		rate     string
+		min, max int
+	}{
+		{"0", 0, 0},
+		{"0.25", 350, 650},
+		{"1", calls, calls},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rate, func(t *testing.T) {
+			setupTest(t, "UPSTREAM_LOG_SAMPLE_RATE", tt.rate)
+			logs := captureLogs(t)
+
+			for range calls {
+				logUpstreamSuccess(context.Background(), "viacep", http.StatusOK, time.Millisecond)
+			}
+			if got := len(findLogs(logs(), "upstream call succeeded")); got < tt.min || got > tt.max {
+				t.Errorf("logged %d of %d successes, want between %d and %d", got, calls, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestUpstreamFailuresAlwaysLogged(t *testing.T) {
+	setupWithWeatherAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"code":9999,"message":"Internal application error."}}`, http.StatusInternalServerError)
+	}, "UPSTREAM_LOG_SAMPLE_RATE", "0")
+	logs := captureLogs(t)
+
+	assertError(t, getTemperature(t, "/temperature/01001000"), http.StatusServiceUnavailable, "weather_service_unavailable")
+
+	entries := logs()
+	if len(findLogs(entries, "WeatherAPI returned status 500")) == 0 {
+		t.Errorf("failure not logged with UPSTREAM_LOG_SAMPLE_RATE=0: %v", entries)
+	}
+	if n := len(findLogs(entries, "upstream call succeeded")); n != 0 {
+		t.Errorf("logged %d successes with UPSTREAM_LOG_SAMPLE_RATE=0", n)
+	}
+}
+
+func TestLogsCarryRequestCEPAndCity(t *testing.T) {
+	setupWithWeatherAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"code":9999,"message":"Internal application error."}}`, http.StatusInternalServerError)
+	})
+	logs := captureLogs(t)
+
+	getTemperature(t, "/temperature/01001000")
+
+	// Registrado dentro do provedor de clima, que só recebe a cidade
+	found := findLogs(logs(), "WeatherAPI returned status 500")
+	if len(found) == 0 {
+		t.Fatal("WeatherAPI failure not logged")
+	}
+	for _, e := range found {
+		if e["cep"] != "01001000" || e["city"] != "São Paulo" {
+			t.Errorf("log %v, want cep 01001000 and city São Paulo", e)
+		}
+	}
+}
+
+func TestTraceHandlerAddsRequestCEPAndCity(t *testing.T) {
+	logs := captureLogs(t)
+
+	ctx := withCity(withCEP(context.Background(), "01001000"), "São Paulo")
+	slog.InfoContext(ctx, "plain log")
+	slog.InfoContext(context.Background(), "no request")
+
+	found := findLogs(logs(), "plain log")
+	if len(found) != 1 || found[0]["cep"] != "01001000" || found[0]["city"] != "São Paulo" {
+		t.Errorf("logs %v, want cep 01001000 and city São Paulo", found)
+	}
+	for _, e := range findLogs(logs(), "no request") {
+		if _, ok := e["cep"]; ok {
+			t.Errorf("log without request context carries cep: %v", e)
+		}
+	}
+}
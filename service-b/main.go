@@ -1,83 +1,244 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
+	"math"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
 	"go.opentelemetry.io/otel/exporters/zipkin"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
-	weatherAPIKey = "ef75abdde5f840bca86181556251603"
-	weatherAPIURL = "http://api.weatherapi.com/v1/current.json"
+	weatherAPIURL = "https://api.weatherapi.com/v1/current.json"
+	viaCEPBaseURL = "https://viacep.com.br"
+
+	// Versão do formato das respostas; incrementar quando o formato mudar
+	schemaVersion = "1.1"
 )
 
 type WeatherAPIResponse struct {
 	Current struct {
-		TempC float64 `json:"temp_c"`
+		// Ponteiro para distinguir um campo ausente de uma temperatura de 0°C
+		TempC      *float64 `json:"temp_c"`
+		Humidity   float64  `json:"humidity"`
+		PressureMb float64  `json:"pressure_mb"`
+		WindKph    *float64 `json:"wind_kph"`
+		Condition  struct {
+			Text string `json:"text"`
+		} `json:"condition"`
+		// Horário da observação, em segundos Unix
+		LastUpdatedEpoch int64 `json:"last_updated_epoch"`
 	} `json:"current"`
 	Location struct {
-		Name string `json:"name"`
+		Name      string  `json:"name"`
+		Lat       float64 `json:"lat"`
+		Lon       float64 `json:"lon"`
+		TzID      string  `json:"tz_id"`
+		LocalTime string  `json:"localtime"`
 	} `json:"location"`
 }
 
 type CEPRequest struct {
 	CEP string `json:"cep"`
+	// CEP como o cliente o informou ao Service A, presente quando a
+	// normalização feita lá o alterou
+	RequestedCEP string `json:"requested_cep,omitempty"`
+	City         string `json:"city,omitempty"`
 }
 
 type TemperatureResponse struct {
-	City  string  `json:"city"`
-	TempC float64 `json:"temp_C"`
-	TempF float64 `json:"temp_F"`
-	TempK float64 `json:"temp_K"`
+	SchemaVersion string `json:"schema_version"`
+
+	City string `json:"city"`
+
+	// Apenas as escalas pedidas com ?units= (todas por padrão)
+	TempC *float64 `json:"temp_C,omitempty"`
+	TempF *float64 `json:"temp_F,omitempty"`
+	TempK *float64 `json:"temp_K,omitempty"`
+
+	// Condições atuais; omitidas quando a WeatherAPI não as informa
+	Humidity  *float64 `json:"humidity,omitempty"`
+	WindKph   *float64 `json:"wind_kph,omitempty"`
+	Condition string   `json:"condition,omitempty"`
+
+	// UF e bairro do CEP, ausentes quando o provedor não os informa ou a
+	// cidade veio do cliente
+	State        string `json:"state,omitempty"`
+	Neighborhood string `json:"neighborhood,omitempty"`
+
+	// Presentes apenas quando o CEP consultado difere do informado, seja pela
+	// normalização (ex.: "01001-000") ou pela correção de FUZZY_CEP
+	RequestedCEP string `json:"requested_cep,omitempty"`
+	ResolvedCEP  string `json:"resolved_cep,omitempty"`
+
+	// Presentes apenas quando FUZZY_CEP corrigiu o CEP informado
+	CorrectedCEP string `json:"corrected_cep,omitempty"`
+	Fuzzy        bool   `json:"fuzzy,omitempty"`
+
+	// Campos adicionais, presentes apenas com ?extra=true
+	Region            string     `json:"region,omitempty"`
+	StationDistanceKm *float64   `json:"station_distance_km,omitempty"`
+	PressureMb        *float64   `json:"pressure_mb,omitempty"`
+	DewpointC         *float64   `json:"dewpoint_C,omitempty"`
+	LocalTime         *Timestamp `json:"local_time,omitempty"`
+	ObservedAt        *Timestamp `json:"observed_at,omitempty"` // horário da observação do provedor
+	ServedAt          *Timestamp `json:"served_at,omitempty"`   // horário em que a resposta foi gerada
+
+	// Temperaturas de localidades próximas, presentes apenas com ?nearby=N
+	Nearby []NearbyTemperature `json:"nearby,omitempty"`
+
+	// Indicam que algum dado opcional não pôde ser obtido; a temperatura
+	// principal continua válida
+	Partial  bool     `json:"partial,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Origem dos dados, arredondamento aplicado e tentativas por provedor,
+	// presentes apenas com ?debug=true
+	Sources   *Sources      `json:"sources,omitempty"`
+	Precision *Precision    `json:"precision,omitempty"`
+	Meta      *ResponseMeta `json:"meta,omitempty"`
+}
+
+// addWarning marca a resposta como parcial, registrando o enriquecimento que falhou
+func (r *TemperatureResponse) addWarning(warning string) {
+	r.Partial = true
+	r.Warnings = append(r.Warnings, warning)
+}
+
+// Resposta com NO_TEMP_AS_200=true quando não há dados de clima para a
+// cidade: as temperaturas vão como null
+type UnavailableTemperatureResponse struct {
+	SchemaVersion string   `json:"schema_version"`
+	City          string   `json:"city"`
+	TempAvailable bool     `json:"temp_available"`
+	TempC         *float64 `json:"temp_C"`
+	TempF         *float64 `json:"temp_F"`
+	TempK         *float64 `json:"temp_K"`
+}
+
+// Resposta compacta para clientes com pouca banda (apenas Celsius)
+type MinimalTemperatureResponse struct {
+	SchemaVersion string  `json:"schema_version"`
+	TempC         float64 `json:"temp_C"`
 }
 
 type ViaCEPResponse struct {
 	Localidade string `json:"localidade"`
+	UF         string `json:"uf"`
+	Bairro     string `json:"bairro"`
+}
+
+// Address é a localidade resolvida a partir de um CEP
+type Address struct {
+	City         string
+	UF           string
+	Neighborhood string
+	Provider     string // provedor que resolveu o CEP
+
+	// Coordenadas do CEP, quando o provedor as informa (apenas a BrasilAPI)
+	Coordinates *Coordinates
+}
+
+// newExporter cria o exporter de spans correspondente a um item de TRACE_EXPORTERS
+func newExporter(name string, profile tracingProfile) (sdktrace.SpanExporter, error) {
+	switch name {
+	case "zipkin":
+		// OTEL_EXPORTER_ZIPKIN_ENDPOINT tem precedência sobre o perfil do ambiente
+		endpoint := cfg.ZipkinEndpoint
+		if endpoint == "" {
+			endpoint = profile.ZipkinEndpoint
+		}
+		exporter, err := zipkin.New(
+			endpoint,
+			zipkin.WithLogger(log.New(os.Stdout, "ZIPKIN", log.LstdFlags)),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zipkin exporter: %w", err)
+		}
+		return exporter, nil
+	case "otlp":
+		// Endpoint e demais opções vêm das variáveis padrão OTEL_EXPORTER_OTLP_*
+		var (
+			exporter sdktrace.SpanExporter
+			err      error
+		)
+		if cfg.OTLPProtocol == "http/protobuf" {
+			exporter, err = otlptracehttp.New(context.Background())
+		} else {
+			exporter, err = otlptracegrpc.New(context.Background())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create otlp exporter: %w", err)
+		}
+		return exporter, nil
+	case "stdout":
+		exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout exporter: %w", err)
+		}
+		return exporter, nil
+	}
+	return nil, fmt.Errorf("unknown trace exporter %q", name)
 }
 
 func initTracer() (*sdktrace.TracerProvider, error) {
-	// Configuração robusta do exporter Zipkin
-	exporter, err := zipkin.New(
-		"http://zipkin:9411/api/v2/spans",
-		zipkin.WithLogger(log.New(os.Stdout, "ZIPKIN", log.LstdFlags)),
-	)
+	profile, err := loadTracingProfile(cfg.DeployEnv, cfg.TracingProfilesFile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create zipkin exporter: %w", err)
+		return nil, err
+	}
+	// OTEL_TRACES_SAMPLER_ARG tem precedência sobre a taxa do perfil
+	if cfg.TracesSamplerArg != "" {
+		ratio, err := strconv.ParseFloat(cfg.TracesSamplerArg, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			return nil, fmt.Errorf("invalid OTEL_TRACES_SAMPLER_ARG %q: must be a ratio between 0 and 1", cfg.TracesSamplerArg)
+		}
+		profile.SamplerRatio = ratio
 	}
+	slog.Info("Using tracing profile", "profile", cfg.DeployEnv, "sampler_ratio", profile.SamplerRatio)
 
-	// Configuração do resource com metadados do serviço
-	res, err := resource.New(
-		context.Background(),
-		resource.WithAttributes(
-			semconv.ServiceName("service-b"),
-			semconv.ServiceVersion("1.0.0"),
-			attribute.String("environment", "production"),
-		),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create resource: %w", err)
+	var exporters []sdktrace.SpanExporter
+	for _, name := range cfg.TraceExporters {
+		exporter, err := newExporter(name, profile)
+		if err != nil {
+			slog.Warn("Skipping trace exporter", "exporter", name, "error", err)
+			continue
+		}
+		exporters = append(exporters, exporter)
+	}
+	if len(exporters) == 0 {
+		return nil, fmt.Errorf("no trace exporter available from %v", cfg.TraceExporters)
 	}
 
-	// Criação do TracerProvider
-	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(exporter),
-		sdktrace.WithResource(res),
-		sdktrace.WithSampler(sdktrace.AlwaysSample()),
-	)
+	tp, err := newTracerProvider(exporters, profile.SamplerRatio)
+	if err != nil {
+		return nil, err
+	}
 
 	// Configuração do propagador para tracing distribuído
 	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
@@ -89,207 +250,844 @@ func initTracer() (*sdktrace.TracerProvider, error) {
 	return tp, nil
 }
 
-func fetchCityFromCEP(ctx context.Context, cep string) (string, error) {
-	tracer := otel.Tracer("service-b")
-	ctx, span := tracer.Start(ctx, "fetch-city-from-cep")
-	defer span.End()
-
-	span.SetAttributes(
-		attribute.String("cep", cep),
-		attribute.String("api.url", "viacep.com.br"),
-	)
-
-	url := fmt.Sprintf("https://viacep.com.br/ws/%s/json/", cep)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// newTracerProvider cria o TracerProvider com o resource do serviço e um batch
+// span processor por exporter: se um destino falhar, os demais continuam
+// recebendo os spans
+func newTracerProvider(exporters []sdktrace.SpanExporter, samplerRatio float64) (*sdktrace.TracerProvider, error) {
+	// Configuração do resource com metadados do serviço
+	attrs := []attribute.KeyValue{
+		semconv.ServiceName("service-b"),
+		semconv.ServiceVersion("1.0.0"),
+		attribute.String("environment", cfg.DeployEnv),
+	}
+	// Região e zona da implantação, para filtrar traces em ambientes multirregião
+	if cfg.DeployRegion != "" {
+		attrs = append(attrs, semconv.CloudRegion(cfg.DeployRegion))
+	}
+	if cfg.DeployZone != "" {
+		attrs = append(attrs, semconv.CloudAvailabilityZone(cfg.DeployZone))
+	}
+	res, err := resource.New(context.Background(), resource.WithAttributes(attrs...))
 	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "Failed to create request")
-		return "", err
+		return nil, fmt.Errorf("failed to create resource: %w", err)
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "API request failed")
-		return "", err
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(samplerRatio))),
 	}
-	defer resp.Body.Close()
+	for _, exporter := range exporters {
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+	return sdktrace.NewTracerProvider(opts...), nil
+}
 
-	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+// countingReader conta os bytes lidos do corpo da requisição
+type countingReader struct {
+	io.Reader
+	n int64
+}
 
-	if resp.StatusCode == http.StatusBadRequest {
-		span.SetStatus(codes.Error, "invalid zipcode")
-		return "", fmt.Errorf("invalid zipcode")
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// requireCompleteBody rejeita com 400 corpos menores que o Content-Length
+// declarado, quando VALIDATE_CONTENT_LENGTH está ativo. O net/http já limita a
+// leitura ao Content-Length e devolve io.ErrUnexpectedEOF quando a conexão
+// termina antes dele.
+func requireCompleteBody(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.ValidateContentLength || r.ContentLength < 0 {
+			next(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			writeJSONError(w, http.StatusBadRequest, "truncated body", "truncated_body")
+			return
+		}
+		if err != nil {
+			writeBodyError(w, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next(w, r)
 	}
+}
 
-	if resp.StatusCode == http.StatusNotFound {
-		span.SetStatus(codes.Error, "can not find zipcode")
-		return "", fmt.Errorf("can not find zipcode")
+// withRequestTimeout limita cada requisição a REQUEST_TIMEOUT. O prazo vale
+// para todo o contexto da requisição, inclusive as consultas aos provedores,
+// que são canceladas quando ele se esgota; o cancelamento pelo Service A
+// (prazo dele esgotado) chega pelo mesmo contexto
+func withRequestTimeout(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), cfg.RequestTimeout)
+		defer cancel()
+		next(w, r.WithContext(ctx))
 	}
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "Failed to read response")
-		return "", err
+// requestTimedOut indica se o prazo de REQUEST_TIMEOUT da requisição se esgotou
+func requestTimedOut(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
+
+func writeRequestTimeout(w http.ResponseWriter) {
+	writeJSONError(w, http.StatusGatewayTimeout, "request timeout", "request_timeout")
+}
+
+// Cliente HTTP compartilhado pelas chamadas externas, para reaproveitar conexões
+var httpClient *http.Client
+
+func newHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = cfg.UpstreamDisableKeepAlive
+	// O timeout limita a chamada inteira; prazos menores no contexto da
+	// requisição continuam valendo
+	return &http.Client{Transport: transport, Timeout: cfg.HTTPClientTimeout}
+}
+
+// setAttributes registra atributos no span truncando valores de texto em
+// TRACE_ATTRIBUTE_MAX_LENGTH caracteres, para que corpos de erro e URLs
+// longas não inflem o armazenamento dos traces. Bytes que não são UTF-8
+// válido são substituídos, pois os exportadores os rejeitam.
+func setAttributes(span trace.Span, attrs ...attribute.KeyValue) {
+	for i, attr := range attrs {
+		if attr.Value.Type() == attribute.STRING {
+			value := strings.ToValidUTF8(attr.Value.AsString(), "\uFFFD")
+			attrs[i] = attribute.String(string(attr.Key), truncate(value, cfg.TraceAttributeMaxLength))
+		}
 	}
+	span.SetAttributes(attrs...)
+}
 
-	var viaCEPResp ViaCEPResponse
-	if err := json.Unmarshal(body, &viaCEPResp); err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "Failed to decode response")
-		return "", err
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
 	}
+	return string([]rune(s)[:max-1]) + "…"
+}
+
+// phaseSpan representa uma fase registrada como eventos no span pai,
+// usada quando TRACE_VERBOSITY=minimal
+type phaseSpan struct {
+	trace.Span
+	name string
+}
+
+func (p phaseSpan) End(...trace.SpanEndOption) {
+	p.Span.AddEvent(p.name + " end")
+}
+
+func startPhase(ctx context.Context, tracer trace.Tracer, name string) (context.Context, trace.Span) {
+	var span trace.Span
+	if cfg.TraceVerbosity != "minimal" {
+		ctx, span = tracer.Start(ctx, name)
+	} else {
+		span = trace.SpanFromContext(ctx)
+		span.AddEvent(name + " start")
+		span = phaseSpan{Span: span, name: name}
+	}
+	if timings := phaseTimingsFrom(ctx); timings != nil {
+		span = timedSpan{Span: span, name: name, start: time.Now(), timings: timings}
+	}
+	return ctx, span
+}
+
+// fetchCityFromCEP consulta os provedores de CEP_PROVIDERS em ordem, passando
+// ao seguinte apenas quando o anterior falha; respostas definitivas (CEP
+// inválido ou inexistente) encerram a busca
+func fetchCityFromCEP(ctx context.Context, cep string) (Address, error) {
+	tracer := otel.Tracer("service-b")
+	ctx, span := startPhase(ctx, tracer, "fetch-city-from-cep")
+	defer span.End()
+
+	setAttributes(span, attribute.String("cep", cep))
 
-	if viaCEPResp.Localidade == "" {
+	if negativeCache.has(cep) {
+		setAttributes(span, attribute.Bool("cache.negative_hit", true))
 		span.SetStatus(codes.Error, "city not found")
-		return "", fmt.Errorf("city not found")
+		return Address{}, fmt.Errorf("city not found")
 	}
 
-	span.SetAttributes(attribute.String("city", viaCEPResp.Localidade))
-	return viaCEPResp.Localidade, nil
+	if addr, ok := addressCache.get(cep); ok {
+		setAttributes(span, attribute.Bool("cache.hit", true))
+		recordCEPSource(ctx, addr.Provider, true)
+		return addr, nil
+	}
+
+	if addr, ok := throttle.recent("cep", cep); ok {
+		setAttributes(span, attribute.Bool("upstream.throttled", true))
+		recordCEPSource(ctx, addr.(Address).Provider, true)
+		return addr.(Address), nil
+	}
+
+	var err error
+	for _, resolver := range cepResolvers {
+		var addr Address
+		addr, err = resolver.Resolve(ctx, cep)
+		if err == nil {
+			setAttributes(span,
+				attribute.String("cep.provider", resolver.Name()),
+				attribute.String("city", addr.City),
+				attribute.String("uf", addr.UF),
+				attribute.String("neighborhood", addr.Neighborhood),
+			)
+			throttle.record("cep", cep, addr)
+			addressCache.set(cep, addr)
+			recordCEPSource(ctx, resolver.Name(), false)
+			return addr, nil
+		}
+		if isDefinitiveCEPError(err) {
+			break
+		}
+		logf(ctx, "%s lookup failed: %v", resolver.Name(), err)
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, "Failed to resolve CEP")
+	if isCEPNotFound(err) {
+		negativeCache.add(cep)
+	}
+	return Address{}, err
+}
+
+// cachedResolution devolve o endereço e o clima do CEP quando ambos já estão em
+// cache, sem criar spans; ok é falso se algum deles precisar ser consultado
+func cachedResolution(ctx context.Context, cep string) (addr Address, weather Weather, ok bool) {
+	if negativeCache.has(cep) {
+		return Address{}, Weather{}, false
+	}
+	addr, ok = addressCache.get(cep)
+	if !ok {
+		var recent any
+		if recent, ok = throttle.recent("cep", cep); !ok {
+			return Address{}, Weather{}, false
+		}
+		addr = recent.(Address)
+	}
+	recentWeather, ok := throttle.recent(weatherProvider.Name(), addr.City)
+	if !ok {
+		return Address{}, Weather{}, false
+	}
+	recordCEPSource(ctx, addr.Provider, true)
+	recordWeatherSource(ctx, weatherProvider.Name(), true)
+	return addr, recentWeather.(Weather), true
 }
 
-func fetchTemperature(ctx context.Context, city string) (float64, error) {
+// Temperature consulta o clima atual da cidade na WeatherAPI, protegida pelo
+// throttle e pelo circuitBreaker do provedor
+func (weatherAPIProvider) Temperature(ctx context.Context, city string) (Weather, error) {
 	tracer := otel.Tracer("service-b")
-	ctx, span := tracer.Start(ctx, "fetch-temperature")
+	ctx, span := startPhase(ctx, tracer, "fetch-temperature")
 	defer span.End()
 
-	span.SetAttributes(
+	setAttributes(span,
 		attribute.String("city", city),
 		attribute.String("weather.api", "weatherapi.com"),
 	)
 
-	encodedCity := url.QueryEscape(city)
-	url := fmt.Sprintf("%s?key=%s&q=%s&aqi=no", weatherAPIURL, weatherAPIKey, encodedCity)
-	span.SetAttributes(attribute.String("api.url", url))
+	if weather, ok := throttle.recent("weatherapi", city); ok {
+		setAttributes(span, attribute.Bool("upstream.throttled", true))
+		recordWeatherSource(ctx, "weatherapi", true)
+		return weather.(Weather), nil
+	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "Failed to create request")
-		return 0, fmt.Errorf("failed to create request: %w", err)
+	breaker := breakers.get("weatherapi")
+	if !breaker.allow(ctx) {
+		setAttributes(span, attribute.Bool("circuit.open", true))
+		span.SetStatus(codes.Error, "Circuit open")
+		return Weather{}, fmt.Errorf("WeatherAPI: %w", errCircuitOpen)
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	encodedCity := url.QueryEscape(city)
+	key, keyIndex := weatherKeys.next()
+	url := fmt.Sprintf("%s?key=%s&q=%s&aqi=no", cfg.WeatherAPIURL, key, encodedCity)
+	// A chave não é registrada no span, apenas sua posição na lista
+	setAttributes(span,
+		attribute.String("api.url", fmt.Sprintf("%s?q=%s&aqi=no", cfg.WeatherAPIURL, encodedCity)),
+		attribute.Int("weatherapi.key_index", keyIndex),
+	)
+
+	start := time.Now()
+	resp, err := retryGet(ctx, url, retryPolicy{
+		provider:    "weatherapi",
+		maxAttempts: cfg.WeatherAPIRetryMaxAttempts,
+		baseDelay:   cfg.WeatherAPIRetryBaseDelay,
+		timeout:     cfg.WeatherAPITimeout,
+		classify:    weatherAPIRetryable,
+	}, breaker)
 	if err != nil {
+		logf(ctx, "WeatherAPI request failed: %v", err)
+		setAttributes(span, attribute.Bool("error.retryable", isRetryable(err, 0)))
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "API request failed")
-		return 0, fmt.Errorf("API request failed: %w", err)
+		return Weather{}, fmt.Errorf("API request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+	setAttributes(span, attribute.Int("http.status_code", resp.StatusCode))
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
+		logf(ctx, "WeatherAPI returned status %d: %s", resp.StatusCode, body)
+		apiErr := parseWeatherAPIError(body)
+		if weatherKeys.report(keyIndex, apiErr) {
+			span.AddEvent("weather api key quota exhausted")
+		}
+		setAttributes(span, attribute.Bool("error.retryable", isRetryableWeatherError(apiErr, resp.StatusCode)))
+		span.RecordError(apiErr)
 		span.SetStatus(codes.Error, "API returned error")
-		return 0, fmt.Errorf("API error: %s", string(body))
+		return Weather{}, apiErr
 	}
 
 	var weatherResp WeatherAPIResponse
 	if err := json.NewDecoder(resp.Body).Decode(&weatherResp); err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to decode response")
-		return 0, fmt.Errorf("failed to decode response: %w", err)
+		return Weather{}, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	if weatherResp.Current.TempC == 0 {
+	if weatherResp.Current.TempC == nil {
 		span.SetStatus(codes.Error, "Invalid temperature data")
-		return 0, fmt.Errorf("invalid temperature data")
+		return Weather{}, errNoTemperature
 	}
 
-	span.SetAttributes(
-		attribute.Float64("temperature.c", weatherResp.Current.TempC),
+	setAttributes(span,
+		attribute.Float64("temperature.c", *weatherResp.Current.TempC),
 		attribute.String("location", weatherResp.Location.Name),
+		attribute.Float64("weather.humidity", weatherResp.Current.Humidity),
+		attribute.String("weather.condition", weatherResp.Current.Condition.Text),
 	)
+	if weatherResp.Current.WindKph != nil {
+		setAttributes(span, attribute.Float64("weather.wind_kph", *weatherResp.Current.WindKph))
+	}
 
-	return weatherResp.Current.TempC, nil
+	weather := Weather{
+		TempC:      *weatherResp.Current.TempC,
+		Humidity:   weatherResp.Current.Humidity,
+		PressureMb: weatherResp.Current.PressureMb,
+		WindKph:    weatherResp.Current.WindKph,
+		Condition:  weatherResp.Current.Condition.Text,
+		Station:    Coordinates{Lat: weatherResp.Location.Lat, Lon: weatherResp.Location.Lon},
+	}
+	if weatherResp.Current.LastUpdatedEpoch > 0 {
+		weather.LastUpdated = time.Unix(weatherResp.Current.LastUpdatedEpoch, 0)
+	}
+	if weatherResp.Location.LocalTime != "" {
+		localTime, err := parseLocalTime(weatherResp.Location.LocalTime, weatherResp.Location.TzID)
+		if err != nil {
+			logf(ctx, "failed to parse local time: %v", err)
+			span.RecordError(err)
+		} else {
+			weather.LocalTime = localTime
+		}
+	}
+	throttle.record("weatherapi", city, weather)
+	recordWeatherSource(ctx, "weatherapi", false)
+	logUpstreamSuccess(ctx, "weatherapi", resp.StatusCode, time.Since(start))
+	return weather, nil
 }
-func handleTemperature(w http.ResponseWriter, r *http.Request) {
-	tracer := otel.Tracer("service-b")
-	ctx, span := tracer.Start(r.Context(), "handleTemperature")
-	defer span.End()
 
-	span.SetAttributes(
+// checkUpstreams faz uma consulta de teste à WeatherAPI na inicialização, para
+// que chave inválida ou falta de conectividade apareçam antes do primeiro acesso
+func checkUpstreams(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if _, err := weatherProvider.Temperature(ctx, cfg.StartupCheckCity); err != nil {
+		return fmt.Errorf("weather API check for %q failed: %w", cfg.StartupCheckCity, err)
+	}
+	return nil
+}
+
+// startRequest abre o span raiz de uma requisição de temperatura com os
+// atributos comuns às rotas POST e GET
+func startRequest(r *http.Request, name string) (context.Context, trace.Span) {
+	ctx, span := otel.Tracer("service-b").Start(withPhaseTimings(r.Context()), name)
+	recordRequestSpan(ctx, span)
+	setAttributes(span,
 		attribute.String("http.method", r.Method),
 		attribute.String("http.path", r.URL.Path),
 	)
+	return ctx, span
+}
 
+// decodeCEPRequest lê o corpo de POST /temperature, rejeitando campos
+// desconhecidos e a falta do CEP quando a cidade não foi informada
+func decodeCEPRequest(r io.Reader) (CEPRequest, error) {
 	var req CEPRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		// O pacote json não exporta um tipo para este erro
+		if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			return CEPRequest{}, unknownFieldError(strings.Trim(name, `"`))
+		}
+		return CEPRequest{}, err
+	}
+	if req.CEP == "" && req.City == "" {
+		return CEPRequest{}, errMissingCEP
+	}
+	return req, nil
+}
+
+// handleTemperature atende POST /temperature, com o CEP no corpo JSON
+func handleTemperature(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	ctx, span := startRequest(r, "handleTemperature")
+	defer span.End()
+	defer logSlowRequest(ctx, start)
+
+	reqBody := &countingReader{Reader: r.Body}
+	req, err := decodeCEPRequest(reqBody)
+	setAttributes(span, attribute.Int64("http.request_content_length", reqBody.n))
+	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Invalid request body")
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		writeBodyError(w, err)
 		return
 	}
 
-	span.SetAttributes(attribute.String("cep", req.CEP))
+	respondTemperature(ctx, w, r, req)
+}
+
+// handleTemperaturePath atende GET /temperature/{cep}
+func handleTemperaturePath(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	ctx, span := startRequest(r, "handleTemperaturePath")
+	defer span.End()
+	defer logSlowRequest(ctx, start)
+
+	respondTemperature(ctx, w, r, CEPRequest{CEP: r.PathValue("cep")})
+}
+
+// respondTemperature resolve o CEP, consulta o clima e escreve a resposta; é
+// o fluxo comum às rotas POST e GET
+func respondTemperature(ctx context.Context, w http.ResponseWriter, r *http.Request, req CEPRequest) {
+	span := trace.SpanFromContext(ctx)
 
-	city, err := fetchCityFromCEP(ctx, req.CEP)
+	setAttributes(span, attribute.String("cep", req.CEP))
+	resolvedCEP := req.CEP
+	requestedCEP := req.CEP
+	if req.RequestedCEP != "" {
+		requestedCEP = req.RequestedCEP
+	}
+	ctx = withCEP(ctx, resolvedCEP)
+	ctx = withSources(ctx)
+	ctx = withAttempts(ctx)
+
+	nearby, err := parseNearby(r)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Invalid nearby parameter")
+		writeJSONError(w, http.StatusBadRequest, err.Error(), "invalid_nearby")
+		return
+	}
+	units, err := parseUnits(r.URL.Query().Get("units"))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Invalid units parameter")
+		writeJSONError(w, http.StatusBadRequest, err.Error(), "invalid_units")
+		return
+	}
+
+	var (
+		addr    Address
+		weather Weather
+		fullHit bool
+		fuzzy   bool
+	)
+	if cfg.CollapseCacheHitSpans && req.City == "" {
+		addr, weather, fullHit = cachedResolution(ctx, resolvedCEP)
+	}
+	if fullHit {
+		// Tudo em cache: o span da requisição basta, sem os filhos das
+		// consultas que não foram feitas
+		setAttributes(span, attribute.Bool("cache.full_hit", true))
+	} else if req.City != "" {
+		// Cidade informada pelo cliente (ACCEPT_CITY no Service A): dispensa a
+		// consulta do CEP
+		setAttributes(span, attribute.Bool("cep.bypassed", true))
+		addr = Address{City: req.City}
+	} else {
+		addr, err = fetchCityFromCEP(ctx, resolvedCEP)
+		if err != nil && cfg.FuzzyCEP && isCEPNotFound(err) {
+			if fuzzyAddr, corrected, fuzzyErr := fuzzyResolveCEP(ctx, resolvedCEP); fuzzyErr == nil {
+				addr, resolvedCEP, fuzzy, err = fuzzyAddr, corrected, true, nil
+			}
+		}
+	}
+	if err != nil && requestTimedOut(ctx) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Request timeout")
+		writeRequestTimeout(w)
+		return
+	}
+	if errors.Is(err, errCircuitOpen) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Zipcode service unavailable")
+		writeJSONError(w, http.StatusServiceUnavailable, "zipcode service unavailable", "zipcode_service_unavailable")
+		return
+	}
 	if err != nil {
 		span.RecordError(err)
 		switch err.Error() {
 		case "invalid zipcode":
 			span.SetStatus(codes.Error, "Invalid zipcode")
-			http.Error(w, "invalid zipcode", http.StatusUnprocessableEntity)
-		case "city not found":
+			writeJSONError(w, http.StatusUnprocessableEntity, "invalid zipcode", "invalid_zipcode")
+		case "city not found", "can not find zipcode":
 			span.SetStatus(codes.Error, "Zipcode not found")
-			http.Error(w, "can not find zipcode", http.StatusNotFound)
+			writeJSONError(w, http.StatusNotFound, "can not find zipcode", "zipcode_not_found")
 		default:
+			logf(ctx, "failed to fetch city: %v", err)
 			span.SetStatus(codes.Error, "Failed to fetch city")
-			http.Error(w, "failed to fetch city", http.StatusInternalServerError)
+			writeJSONError(w, http.StatusInternalServerError, "failed to fetch city", "city_fetch_failed")
 		}
 		return
 	}
 
-	tempC, err := fetchTemperature(ctx, city)
+	city := addr.City
+	ctx = withCity(ctx, city)
+	setAttributes(span,
+		attribute.String("state", addr.UF),
+		attribute.String("neighborhood", addr.Neighborhood),
+	)
+
+	// Auditado assim que o CEP é resolvido, qualquer que seja o desfecho da
+	// consulta de clima
+	if req.City == "" {
+		auditResolution(ctx, resolvedCEP, city)
+	}
+
+	if isWeatherSkipped(city) {
+		setAttributes(span, attribute.Bool("weather.skipped", true))
+		if cfg.NoTempAs200 {
+			writeNoTemperature(ctx, w, city)
+			return
+		}
+		span.SetStatus(codes.Error, "Weather unavailable for city")
+		writeJSONError(w, http.StatusUnprocessableEntity, "weather unavailable for city", "weather_unavailable_for_city")
+		return
+	}
+
+	if !fullHit {
+		weather, err = weatherProvider.Temperature(ctx, city)
+	}
+	if errors.Is(err, errNoTemperature) && cfg.NoTempAs200 {
+		writeNoTemperature(ctx, w, city)
+		return
+	}
+	if err != nil && requestTimedOut(ctx) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Request timeout")
+		writeRequestTimeout(w)
+		return
+	}
 	if err != nil {
 		span.RecordError(err)
+		logf(ctx, "failed to fetch temperature: %v", err)
 		span.SetStatus(codes.Error, "Failed to fetch temperature")
-		http.Error(w, "failed to fetch temperature", http.StatusInternalServerError)
+		m := weatherErrorStatus(err)
+		setAttributes(span, attribute.Int("http.status_code", m.status))
+		writeJSONError(w, m.status, m.message, m.code)
 		return
 	}
 
-	tempF := tempC*1.8 + 32
-	tempK := tempC + 273
+	tempC := roundTemperature(weather.TempC)
+	setAttributes(span, attribute.Float64("temperature.c", tempC))
 
 	response := TemperatureResponse{
-		City:  city,
-		TempC: tempC,
-		TempF: tempF,
-		TempK: tempK,
+		SchemaVersion: schemaVersion,
+		City:          canonicalCity(city, cfg.CityNameForm),
+		WindKph:       weather.WindKph,
+		Condition:     weather.Condition,
+		State:         addr.UF,
+		Neighborhood:  addr.Neighborhood,
+	}
+	// Só as escalas pedidas são convertidas
+	if units.C {
+		response.TempC = &tempC
+	}
+	if units.F {
+		tempF := roundTemperature(celsiusToFahrenheit(weather.TempC))
+		response.TempF = &tempF
+		setAttributes(span, attribute.Float64("temperature.f", tempF))
+	}
+	if units.K {
+		tempK := roundTemperature(celsiusToKelvin(weather.TempC))
+		response.TempK = &tempK
+		setAttributes(span, attribute.Float64("temperature.k", tempK))
+	}
+	// A WeatherAPI informa umidade 0 quando não tem o dado
+	if weather.Humidity > 0 {
+		humidity := weather.Humidity
+		response.Humidity = &humidity
+	}
+	if resolvedCEP != requestedCEP {
+		response.RequestedCEP = requestedCEP
+		response.ResolvedCEP = resolvedCEP
+		if fuzzy {
+			response.CorrectedCEP = resolvedCEP
+			response.Fuzzy = true
+		}
+		setAttributes(span, attribute.String("cep.resolved", resolvedCEP))
 	}
 
-	span.SetAttributes(
-		attribute.Float64("temperature.c", tempC),
-		attribute.Float64("temperature.f", tempF),
-		attribute.Float64("temperature.k", tempK),
-	)
+	if r.URL.Query().Get("extra") == "true" {
+		response.Region = regionByUF[addr.UF]
+		if addr.Coordinates != nil {
+			distance := math.Round(haversineKm(*addr.Coordinates, weather.Station)*10) / 10
+			response.StationDistanceKm = &distance
+		} else {
+			response.addWarning("station_distance_km unavailable")
+		}
+		// Sem pressão informada pelo provedor, o campo é omitido
+		if weather.PressureMb > 0 {
+			pressure := weather.PressureMb
+			response.PressureMb = &pressure
+		}
+		if weather.Humidity > 0 {
+			dewpoint := roundTemperature(dewPointC(weather.TempC, weather.Humidity))
+			response.DewpointC = &dewpoint
+		}
+		if !weather.LocalTime.IsZero() {
+			localTime := Timestamp(weather.LocalTime)
+			response.LocalTime = &localTime
+		} else {
+			response.addWarning("local_time unavailable")
+		}
+		if !weather.LastUpdated.IsZero() {
+			observedAt := Timestamp(weather.LastUpdated.UTC())
+			response.ObservedAt = &observedAt
+		} else {
+			response.addWarning("observed_at unavailable")
+		}
+		servedAt := Timestamp(time.Now().UTC())
+		response.ServedAt = &servedAt
+	}
+
+	if nearby > 0 {
+		response.Nearby, err = fetchNearbyTemperatures(ctx, city, weather.Station, nearby)
+		if err != nil {
+			logf(ctx, "failed to fetch nearby cities: %v", err)
+			span.RecordError(err)
+			response.addWarning("nearby temperatures incomplete")
+		}
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+	if r.URL.Query().Get("debug") == "true" {
+		response.Sources = sourcesFrom(ctx)
+		response.Meta = &ResponseMeta{Attempts: attemptsFrom(ctx)}
+		precision := temperaturePrecision()
+		response.Precision = &precision
+	}
+
+	if response.Partial {
+		setAttributes(span, attribute.Bool("response.partial", true))
+	}
+
+	var body any = response
+	if cfg.MinimalResponse || r.URL.Query().Get("minimal") == "true" {
+		setAttributes(span, attribute.Bool("response.minimal", true))
+		body = MinimalTemperatureResponse{SchemaVersion: schemaVersion, TempC: tempC}
+	}
+
+	// Com ?nearby= ou ?debug=true a resposta depende de mais do que a
+	// observação da cidade, então não recebe ETag
+	if cfg.ETagEnabled && !weather.LastUpdated.IsZero() && nearby == 0 && r.URL.Query().Get("debug") != "true" {
+		etag := observationETag(r, city, weather.LastUpdated)
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Vary", "Accept")
+		setAttributes(span, attribute.String("http.etag", etag))
+		if etagMatches(r, etag) {
+			setAttributes(span, attribute.Bool("http.not_modified", true))
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	contentType := "application/json"
+	if wantsGeoJSON(r) {
+		coords := featureCoordinates(addr, weather)
+		setAttributes(span,
+			attribute.Bool("response.geojson", true),
+			attribute.Bool("response.geojson.geometry", coords != nil),
+		)
+		body = newGeoJSONFeature(coords, body)
+		contentType = geoJSONContentType
+	}
+
+	n, err := writeJSONAs(w, http.StatusOK, contentType, body)
+	if err != nil {
 		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to write response")
+	}
+	setAttributes(span, attribute.Int("http.response_content_length", n))
+}
+
+// writeNoTemperature responde 200 com temp_available=false para uma cidade
+// válida sem dados de clima (NO_TEMP_AS_200=true)
+func writeNoTemperature(ctx context.Context, w http.ResponseWriter, city string) {
+	span := trace.SpanFromContext(ctx)
+	setAttributes(span, attribute.Bool("temperature.available", false))
+	_, err := writeJSON(w, http.StatusOK, UnavailableTemperatureResponse{
+		SchemaVersion: schemaVersion,
+		City:          canonicalCity(city, cfg.CityNameForm),
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to write response")
+	}
+}
+
+type HealthResponse struct {
+	SchemaVersion string `json:"schema_version"`
+	Status        string `json:"status"`
+}
+
+// handleHealth responde às sondas de saúde com o status de HEALTH_STATUS_CODE;
+// com 204 a resposta não tem corpo
+func handleHealth(w http.ResponseWriter, r *http.Request) {
+	if cfg.HealthStatusCode == http.StatusNoContent {
+		w.WriteHeader(http.StatusNoContent)
+		return
 	}
+	writeJSON(w, cfg.HealthStatusCode, HealthResponse{SchemaVersion: schemaVersion, Status: "ok"})
+}
+
+// ReadinessResponse informa se o serviço pode receber tráfego e o estado do
+// circuito de cada provedor
+type ReadinessResponse struct {
+	SchemaVersion string                  `json:"schema_version"`
+	Status        string                  `json:"status"`
+	Breakers      map[string]breakerState `json:"breakers"`
+}
+
+// handleReady responde 503 enquanto o circuito do provedor de clima estiver
+// aberto: sem ele nenhuma temperatura pode ser obtida. Meio-aberto conta como pronto,
+// para que a chamada de teste chegue a ser feita.
+func handleReady(w http.ResponseWriter, r *http.Request) {
+	states := breakers.states()
+	status, code := "ready", http.StatusOK
+	if states[weatherProvider.Name()] == breakerOpen {
+		status, code = "unavailable", http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, ReadinessResponse{SchemaVersion: schemaVersion, Status: status, Breakers: states})
+}
+
+// registerRoutes registra as rotas da API em mux
+func registerRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /temperature", instrument("/temperature", withRequestTimeout(limitBody(requireCompleteBody(handleTemperature)))))
+	mux.HandleFunc("/temperature", instrument("/temperature", methodNotAllowed(http.MethodPost)))
+	mux.HandleFunc("GET /temperature/{cep}", instrument("/temperature/{cep}", withRequestTimeout(handleTemperaturePath)))
+	mux.HandleFunc("POST /temperature/batch", instrument("/temperature/batch", withRequestTimeout(limitBody(requireCompleteBody(handleTemperatureBatch)))))
+	// Sem método, /temperature/batch conflitaria com GET /temperature/{cep} no mux; cada
+	// método é registrado para responder o 405 em JSON, e não como CEP inválido
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions} {
+		mux.HandleFunc(method+" /temperature/batch", instrument("/temperature/batch", methodNotAllowed(http.MethodPost)))
+	}
+	mux.HandleFunc("/health", instrument("/health", handleHealth))
+	mux.HandleFunc("GET /ready", instrument("/ready", handleReady))
 }
 
 func main() {
+	var err error
+	cfg, err = loadConfig()
+	if err != nil {
+		fatal("Invalid configuration", "error", err)
+	}
+	if err := initLogger(cfg.LogLevel); err != nil {
+		fatal("Invalid LOG_LEVEL", "error", err)
+	}
+	weatherProvider = newWeatherProvider(cfg.WeatherProvider)
+	if cfg.WeatherProvider == "weatherapi" {
+		keyEntries := cfg.WeatherAPIKeys
+		if len(keyEntries) == 0 && cfg.WeatherAPIKey != "" {
+			keyEntries = []string{cfg.WeatherAPIKey}
+		}
+		keys, err := parseWeatherKeys(keyEntries)
+		if err != nil {
+			fatal("WEATHER_API_KEY or WEATHER_API_KEYS not set or invalid", "error", err)
+		}
+		weatherKeys = newWeatherKeyPool(keys, cfg.WeatherAPIKeyCooldown)
+	} else {
+		if cfg.WeatherProvider == "openweathermap" && cfg.OpenWeatherMapAPIKey == "" {
+			fatal("OPENWEATHERMAP_API_KEY not set")
+		}
+		slog.Info("Using weather provider", "provider", weatherProvider.Name())
+	}
+	httpClient = newHTTPClient()
+	throttle = newUpstreamThrottle(cfg.UpstreamMinInterval, cfg.CacheShards)
+	negativeCache = newNotFoundCache(cfg.NegativeCacheTTL, cfg.CacheShards)
+	breakers = newBreakerRegistry(cfg.BreakerFailureThreshold, cfg.BreakerCooldown)
+	cepResolvers = newCEPResolvers(cfg.CEPProviders)
+	addressCache = newCEPCache(cfg.CEPCacheTTL, cfg.CacheShards)
+	if cfg.CEPCacheTTL > 0 {
+		go addressCache.runCleanup(cfg.CEPCacheCleanupInterval)
+	}
+	if cfg.AuditLog {
+		auditSink = logAuditSink{}
+	}
+	if cfg.UpstreamDisableKeepAlive {
+		slog.Info("Keep-alive disabled for upstream connections")
+	}
+
 	tp, err := initTracer()
 	if err != nil {
-		log.Fatalf("Failed to initialize tracer: %v", err)
+		fatal("Failed to initialize tracer", "error", err)
+	}
+
+	if cfg.PrewarmConnections {
+		prewarmConnections(context.Background())
 	}
-	defer func() {
-		if err := tp.Shutdown(context.Background()); err != nil {
-			log.Printf("Failed to shutdown tracer: %v", err)
+
+	if cfg.StartupUpstreamCheck {
+		if err := checkUpstreams(context.Background()); err != nil {
+			fatal("Startup upstream check failed", "error", err)
 		}
-	}()
+		slog.Info("Startup upstream check passed")
+	}
 
 	// Configuração do servidor HTTP
-	http.HandleFunc("/temperature", handleTemperature)
-	log.Println("Service B listening on :8081")
-	if err := http.ListenAndServe(":8081", nil); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	registerRoutes(http.DefaultServeMux)
+	serveMetrics()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: ":" + cfg.Port, Handler: logRequests(http.DefaultServeMux)}
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("Service B listening", "port", cfg.Port)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		fatal("Failed to start server", "error", err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	// Encerramento: para de aceitar conexões, aguarda as requisições em
+	// andamento por até SHUTDOWN_GRACE_PERIOD e só então descarrega os spans
+	slog.Info("Shutting down, draining connections", "grace_period", cfg.ShutdownGracePeriod.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownGracePeriod)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Failed to drain connections", "error", err)
+	} else {
+		slog.Info("Server drained")
+	}
+	if err := tp.Shutdown(context.Background()); err != nil {
+		slog.Error("Failed to shutdown tracer", "error", err)
+	} else {
+		slog.Info("Tracer flushed")
 	}
+	slog.Info("Service B stopped")
 }
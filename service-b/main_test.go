@@ -0,0 +1,618 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// testEnv é aplicado antes das variáveis de cada teste: provedor de clima
+// falso, só o ViaCEP e esperas curtas entre tentativas
+var testEnv = []string{
+	"WEATHER_PROVIDER", "mock",
+	"WEATHER_API_KEY", "test-key",
+	"CEP_PROVIDERS", "viacep",
+	"VIACEP_RETRY_BASE_DELAY", "1ms",
+	"WEATHERAPI_RETRY_BASE_DELAY", "1ms",
+}
+
+// setupTest carrega a configuração com as variáveis informadas (pares nome,
+// valor) e recria o estado global usado pelos handlers, como faz main
+func setupTest(t *testing.T, env ...string) {
+	t.Helper()
+	env = append(append([]string{}, testEnv...), env...)
+	for i := 0; i+1 < len(env); i += 2 {
+		t.Setenv(env[i], env[i+1])
+	}
+
+	c, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	cfg = c
+
+	weatherProvider = newWeatherProvider(cfg.WeatherProvider)
+	weatherKeys = nil
+	if cfg.WeatherProvider == "weatherapi" {
+		keyEntries := cfg.WeatherAPIKeys
+		if len(keyEntries) == 0 {
+			keyEntries = []string{cfg.WeatherAPIKey}
+		}
+		keys, err := parseWeatherKeys(keyEntries)
+		if err != nil {
+			t.Fatalf("parseWeatherKeys: %v", err)
+		}
+		weatherKeys = newWeatherKeyPool(keys, cfg.WeatherAPIKeyCooldown)
+	}
+	httpClient = newHTTPClient()
+	throttle = newUpstreamThrottle(cfg.UpstreamMinInterval, cfg.CacheShards)
+	negativeCache = newNotFoundCache(cfg.NegativeCacheTTL, cfg.CacheShards)
+	breakers = newBreakerRegistry(cfg.BreakerFailureThreshold, cfg.BreakerCooldown)
+	cepResolvers = newCEPResolvers(cfg.CEPProviders)
+	addressCache = newCEPCache(cfg.CEPCacheTTL, cfg.CacheShards)
+	auditSink = nil
+	if cfg.AuditLog {
+		auditSink = logAuditSink{}
+	}
+}
+
+// newViaCEPStub sobe um ViaCEP falso com os endereços informados (CEP -> JSON
+// da resposta). Como o real, responde 400 para CEPs mal formados e
+// {"erro": true} para os inexistentes. Devolve o servidor e o número de
+// consultas recebidas.
+func newViaCEPStub(t *testing.T, addresses map[string]string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		cep := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/ws/"), "/json/")
+		if !isValidCEP(cep) {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		body, ok := addresses[cep]
+		if !ok {
+			body = `{"erro": true}`
+		}
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+const saoPauloAddress = `{"cep": "01001-000", "localidade": "São Paulo", "uf": "SP", "bairro": "Sé"}`
+
+// setupWithViaCEP é setupTest com um ViaCEP falso que conhece apenas o CEP
+// 01001000
+func setupWithViaCEP(t *testing.T, env ...string) *atomic.Int32 {
+	t.Helper()
+	srv, calls := newViaCEPStub(t, map[string]string{"01001000": saoPauloAddress})
+	setupTest(t, append([]string{"VIACEP_URL", srv.URL}, env...)...)
+	return calls
+}
+
+// newWeatherAPIStub sobe uma WeatherAPI falsa. Sem handler, responde a
+// consulta gravada em testdata/weatherapi_current.json. Devolve o servidor e o
+// número de consultas recebidas.
+func newWeatherAPIStub(t *testing.T, handler http.HandlerFunc) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	if handler == nil {
+		current, err := os.ReadFile("testdata/weatherapi_current.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(current)
+		}
+	}
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+// setupWithWeatherAPI é setupWithViaCEP com o provedor weatherapi apontando
+// para uma WeatherAPI falsa (ver newWeatherAPIStub). Devolve o número de
+// consultas de clima recebidas.
+func setupWithWeatherAPI(t *testing.T, handler http.HandlerFunc, env ...string) *atomic.Int32 {
+	t.Helper()
+	srv, calls := newWeatherAPIStub(t, handler)
+	setupWithViaCEP(t, append([]string{
+		"WEATHER_PROVIDER", "weatherapi",
+		"WEATHER_API_URL", srv.URL + "/v1/current.json",
+	}, env...)...)
+	return calls
+}
+
+// recordSpans instala um TracerProvider global que guarda em memória os spans
+// encerrados durante o teste
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+// serve executa a requisição nas rotas registradas por main
+func serve(r *http.Request) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	registerRoutes(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, r)
+	return rec
+}
+
+func postTemperature(t *testing.T, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	return serve(r)
+}
+
+func getTemperature(t *testing.T, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	return serve(httptest.NewRequest(http.MethodGet, target, nil))
+}
+
+func decodeBody[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("invalid JSON body %q: %v", rec.Body.String(), err)
+	}
+	return v
+}
+
+func assertError(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("status = %d, want %d (body %s)", rec.Code, status, rec.Body.String())
+	}
+	body := decodeBody[ErrorResponse](t, rec)
+	if body.Code != code {
+		t.Errorf("code = %q, want %q", body.Code, code)
+	}
+	if body.SchemaVersion != schemaVersion {
+		t.Errorf("schema_version = %q, want %q", body.SchemaVersion, schemaVersion)
+	}
+}
+
+func TestTemperatureWithMockProvider(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		do   func(t *testing.T) *httptest.ResponseRecorder
+	}{
+		{"POST", func(t *testing.T) *httptest.ResponseRecorder {
+			return postTemperature(t, "/temperature", `{"cep": "01001000"}`)
+		}},
+		{"GET", func(t *testing.T) *httptest.ResponseRecorder {
+			return getTemperature(t, "/temperature/01001000")
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			setupWithViaCEP(t)
+
+			rec := tc.do(t)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+			}
+			assertTemperatureSchema(t, rec)
+			got := decodeBody[TemperatureResponse](t, rec)
+			if got.City != "São Paulo" || got.State != "SP" || got.Neighborhood != "Sé" {
+				t.Errorf("location = %q/%q/%q, want São Paulo/SP/Sé", got.City, got.State, got.Neighborhood)
+			}
+			if got.TempC == nil || *got.TempC != 25 {
+				t.Errorf("temp_C = %v, want 25", got.TempC)
+			}
+			if got.TempF == nil || *got.TempF != 77 {
+				t.Errorf("temp_F = %v, want 77", got.TempF)
+			}
+			if got.TempK == nil || *got.TempK != 298.2 {
+				t.Errorf("temp_K = %v, want 298.2", got.TempK)
+			}
+			if got.Condition != "Sunny" {
+				t.Errorf("condition = %q, want Sunny", got.Condition)
+			}
+			if got.Humidity == nil || *got.Humidity != 60 {
+				t.Errorf("humidity = %v, want 60", got.Humidity)
+			}
+			if got.SchemaVersion != schemaVersion {
+				t.Errorf("schema_version = %q, want %q", got.SchemaVersion, schemaVersion)
+			}
+		})
+	}
+}
+
+func TestTemperatureCEPNotFound(t *testing.T) {
+	setupWithViaCEP(t)
+
+	rec := postTemperature(t, "/temperature", `{"cep": "99999999"}`)
+	assertError(t, rec, http.StatusNotFound, "zipcode_not_found")
+}
+
+func TestTemperatureInvalidCEP(t *testing.T) {
+	setupWithViaCEP(t)
+
+	rec := getTemperature(t, "/temperature/123")
+	assertError(t, rec, http.StatusUnprocessableEntity, "invalid_zipcode")
+}
+
+func TestTemperatureWeatherErrorMapping(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"city not found", &weatherAPIError{Code: 1006, Message: "No matching location found."}, http.StatusNotFound, "weather_not_found"},
+		{"invalid key", &weatherAPIError{Code: 2006, Message: "API key is invalid."}, http.StatusBadGateway, "upstream_auth_error"},
+		{"quota exceeded", &weatherAPIError{Code: 2007, Message: "API key has exceeded calls per month quota."}, http.StatusBadGateway, "upstream_auth_error"},
+		{"internal application error", &weatherAPIError{Code: 9999, Message: "Internal application error."}, http.StatusServiceUnavailable, "weather_service_unavailable"},
+		{"unmapped code", &weatherAPIError{Code: 1003, Message: "Parameter q is missing."}, http.StatusInternalServerError, "weather_fetch_failed"},
+		{"provider city not found", fmt.Errorf("openweathermap: %w", errWeatherCityNotFound), http.StatusNotFound, "weather_not_found"},
+		{"provider auth", fmt.Errorf("openweathermap: %w", errWeatherAuth), http.StatusBadGateway, "upstream_auth_error"},
+		{"circuit open", fmt.Errorf("weatherapi: %w", errCircuitOpen), http.StatusServiceUnavailable, "weather_service_unavailable"},
+		{"transport error", errors.New("connection reset by peer"), http.StatusInternalServerError, "weather_fetch_failed"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			setupWithViaCEP(t)
+			weatherProvider = mockWeatherProvider{err: tc.err}
+
+			rec := postTemperature(t, "/temperature", `{"cep": "01001000"}`)
+			assertError(t, rec, tc.status, tc.code)
+		})
+	}
+}
+
+func TestTemperatureReportsNormalizedCEP(t *testing.T) {
+	setupWithViaCEP(t)
+
+	// O Service A envia o CEP normalizado e, à parte, o informado
+	rec := postTemperature(t, "/temperature", `{"cep": "01001000", "requested_cep": "01001-000"}`)
+	assertTemperatureSchema(t, rec)
+	got := decodeBody[TemperatureResponse](t, rec)
+	if got.RequestedCEP != "01001-000" || got.ResolvedCEP != "01001000" {
+		t.Errorf("requested_cep/resolved_cep = %q/%q, want 01001-000/01001000", got.RequestedCEP, got.ResolvedCEP)
+	}
+	if got.Fuzzy || got.CorrectedCEP != "" {
+		t.Errorf("fuzzy/corrected_cep = %v/%q for a normalized CEP", got.Fuzzy, got.CorrectedCEP)
+	}
+
+	rec = getTemperature(t, "/temperature/01001000")
+	if strings.Contains(rec.Body.String(), "requested_cep") || strings.Contains(rec.Body.String(), "resolved_cep") {
+		t.Errorf("unchanged CEP reported as corrected: %s", rec.Body.String())
+	}
+}
+
+func TestTemperatureReportsFuzzyCorrection(t *testing.T) {
+	setupWithViaCEP(t, "FUZZY_CEP", "true")
+
+	// 10001000: os dois primeiros dígitos de 01001000 trocados
+	rec := postTemperature(t, "/temperature", `{"cep": "10001000"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+	}
+	assertTemperatureSchema(t, rec)
+	got := decodeBody[TemperatureResponse](t, rec)
+	if got.RequestedCEP != "10001000" || got.ResolvedCEP != "01001000" || got.CorrectedCEP != "01001000" || !got.Fuzzy {
+		t.Errorf("requested_cep/resolved_cep/corrected_cep/fuzzy = %q/%q/%q/%v, want 10001000/01001000/01001000/true",
+			got.RequestedCEP, got.ResolvedCEP, got.CorrectedCEP, got.Fuzzy)
+	}
+}
+
+func TestFullCacheHitCollapsesSpans(t *testing.T) {
+	weatherCalls := setupWithWeatherAPI(t, nil,
+		"COLLAPSE_CACHE_HIT_SPANS", "true",
+		"UPSTREAM_MIN_INTERVAL", "1m",
+	)
+	recorder := recordSpans(t)
+
+	if rec := getTemperature(t, "/temperature/01001000"); rec.Code != http.StatusOK {
+		t.Fatalf("first request status = %d (body %s)", rec.Code, rec.Body.String())
+	}
+	if n := len(recorder.Ended()); n < 3 {
+		t.Fatalf("first request recorded %d spans, want the request span and its upstream children", n)
+	}
+
+	recorder.Reset()
+	rec := getTemperature(t, "/temperature/01001000")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("cached request status = %d (body %s)", rec.Code, rec.Body.String())
+	}
+	assertTemperatureSchema(t, rec)
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		names := make([]string, len(spans))
+		for i, span := range spans {
+			names[i] = span.Name()
+		}
+		t.Fatalf("full cache hit recorded spans %v, want only handleTemperaturePath", names)
+	}
+	if spans[0].Name() != "handleTemperaturePath" {
+		t.Errorf("span = %s, want handleTemperaturePath", spans[0].Name())
+	}
+	fullHit := false
+	for _, attr := range spans[0].Attributes() {
+		if attr.Key == "cache.full_hit" && attr.Value.AsBool() {
+			fullHit = true
+		}
+	}
+	if !fullHit {
+		t.Error("span lacks cache.full_hit=true")
+	}
+	if n := weatherCalls.Load(); n != 1 {
+		t.Errorf("WeatherAPI received %d calls, want 1", n)
+	}
+}
+
+func TestTemperatureWithAllOptionalFieldsMatchesSchema(t *testing.T) {
+	setupWithWeatherAPI(t, nil)
+
+	rec := postTemperature(t, "/temperature?extra=true&debug=true", `{"cep": "01001000", "requested_cep": "01001-000"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+	}
+	assertTemperatureSchema(t, rec)
+	fields := decodeBody[map[string]json.RawMessage](t, rec)
+	for _, name := range []string{"requested_cep", "resolved_cep", "region", "pressure_mb", "dewpoint_C", "local_time", "observed_at", "served_at", "sources", "precision", "meta"} {
+		if _, ok := fields[name]; !ok {
+			t.Errorf("response lacks %s: %s", name, rec.Body.String())
+		}
+	}
+}
+
+func TestTemperatureMinimalResponse(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		target string
+		env    []string
+	}{
+		{"query", "/temperature/01001000?minimal=true", nil},
+		{"env", "/temperature/01001000", []string{"MINIMAL_RESPONSE", "true"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			setupWithViaCEP(t, tc.env...)
+
+			rec := getTemperature(t, tc.target)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+			}
+			got := decodeBody[map[string]any](t, rec)
+			want := map[string]any{"schema_version": schemaVersion, "temp_C": 25.0}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("body = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestHTTPClientKeepAlive(t *testing.T) {
+	for _, tc := range []struct {
+		env  string
+		want bool
+	}{
+		{"", false},
+		{"false", false},
+		{"true", true},
+	} {
+		t.Run("UPSTREAM_DISABLE_KEEPALIVE="+tc.env, func(t *testing.T) {
+			setupTest(t, "UPSTREAM_DISABLE_KEEPALIVE", tc.env)
+			transport, ok := httpClient.Transport.(*http.Transport)
+			if !ok {
+				t.Fatalf("transport = %T, want *http.Transport", httpClient.Transport)
+			}
+			if transport.DisableKeepAlives != tc.want {
+				t.Errorf("DisableKeepAlives = %v, want %v", transport.DisableKeepAlives, tc.want)
+			}
+		})
+	}
+}
+
+func TestCheckUpstreams(t *testing.T) {
+	setupWithWeatherAPI(t, nil, "STARTUP_CHECK_CITY", "Recife")
+	if err := checkUpstreams(context.Background()); err != nil {
+		t.Errorf("checkUpstreams: %v", err)
+	}
+}
+
+func TestCheckUpstreamsFailsOnAuthError(t *testing.T) {
+	var city atomic.Value
+	calls := setupWithWeatherAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		city.Store(r.URL.Query().Get("q"))
+		w.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(w, `{"error":{"code":2006,"message":"API key provided is invalid"}}`)
+	}, "STARTUP_CHECK_CITY", "Recife")
+
+	err := checkUpstreams(context.Background())
+	var apiErr *weatherAPIError
+	if !errors.As(err, &apiErr) || apiErr.Code != 2006 {
+		t.Fatalf("checkUpstreams error = %v, want WeatherAPI error 2006", err)
+	}
+	if weatherErrorStatus(err).code != "upstream_auth_error" {
+		t.Errorf("error maps to %+v, want upstream_auth_error", weatherErrorStatus(err))
+	}
+	// Erro de chave é definitivo: uma única consulta, pela cidade configurada
+	if calls.Load() != 1 || city.Load() != "Recife" {
+		t.Errorf("WeatherAPI got %d calls for %v, want 1 for Recife", calls.Load(), city.Load())
+	}
+}
+
+// postRaw envia POST path ao servidor por uma conexão TCP crua, declarando
+// contentLength bytes mas enviando apenas body, e encerra a escrita para que
+// o servidor perceba o fim do corpo
+func postRaw(t *testing.T, srv *httptest.Server, path string, contentLength int, body string) *http.Response {
+	t.Helper()
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	fmt.Fprintf(conn, "POST %s HTTP/1.1\r\nHost: test\r\nContent-Type: application/json\r\nContent-Length: %d\r\n\r\n%s",
+		path, contentLength, body)
+	if err := conn.(*net.TCPConn).CloseWrite(); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("reading response: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestTruncatedBodyRejected(t *testing.T) {
+	const body = `{"cep": "01001000"}`
+	tests := []struct {
+		name          string
+		validate      string
+		contentLength int
+		wantStatus    int
+		wantCode      string
+	}{
+		{"truncated", "true", len(body) + 11, http.StatusBadRequest, "truncated_body"},
+		{"complete", "true", len(body), http.StatusOK, ""},
+		// Sem a validação, o JSON completo nos bytes recebidos é aceito
+		{"validation disabled", "false", len(body) + 11, http.StatusOK, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := setupWithViaCEP(t, "VALIDATE_CONTENT_LENGTH", tc.validate)
+			mux := http.NewServeMux()
+			registerRoutes(mux)
+			srv := httptest.NewServer(mux)
+			t.Cleanup(srv.Close)
+
+			var resp *http.Response
+			if tc.contentLength == len(body) {
+				var err error
+				if resp, err = http.Post(srv.URL+"/temperature", "application/json", strings.NewReader(body)); err != nil {
+					t.Fatal(err)
+				}
+				defer resp.Body.Close()
+			} else {
+				resp = postRaw(t, srv, "/temperature", tc.contentLength, body)
+			}
+			if resp.StatusCode != tc.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tc.wantStatus)
+			}
+			if tc.wantCode == "" {
+				return
+			}
+			var errResp ErrorResponse
+			if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil || errResp.Code != tc.wantCode {
+				t.Errorf("code = %q (%v), want %s", errResp.Code, err, tc.wantCode)
+			}
+			if n := calls.Load(); n != 0 {
+				t.Errorf("ViaCEP calls = %d, want 0 for a truncated body", n)
+			}
+		})
+	}
+}
+
+func TestTemperatureRequestTimeout(t *testing.T) {
+	// O ViaCEP só responde depois que o cliente desiste
+	viaCEP := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	t.Cleanup(viaCEP.Close)
+	setupTest(t, "VIACEP_URL", viaCEP.URL, "REQUEST_TIMEOUT", "50ms")
+
+	assertError(t, getTemperature(t, "/temperature/01001000"), http.StatusGatewayTimeout, "request_timeout")
+}
+
+func TestTemperatureMethodNotAllowed(t *testing.T) {
+	calls := setupWithViaCEP(t)
+
+	for _, path := range []string{"/temperature", "/temperature/batch"} {
+		for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+			rec := serve(httptest.NewRequest(method, path, nil))
+			assertError(t, rec, http.StatusMethodNotAllowed, "method_not_allowed")
+			if got := rec.Header().Get("Allow"); got != http.MethodPost {
+				t.Errorf("%s %s: Allow = %q, want POST", method, path, got)
+			}
+		}
+	}
+	if n := calls.Load(); n != 0 {
+		t.Errorf("ViaCEP calls = %d, want 0", n)
+	}
+}
+
+func TestTemperatureBodyTooLarge(t *testing.T) {
+	calls := setupWithViaCEP(t, "MAX_BODY_BYTES", "64")
+	oversized := `{"cep": "` + strings.Repeat("0", 100) + `"}`
+
+	for _, tc := range []struct {
+		name          string
+		contentLength int64
+	}{
+		{"declared length", int64(len(oversized))},
+		// Sem Content-Length o limite só é percebido durante a leitura
+		{"unknown length", -1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/temperature", strings.NewReader(oversized))
+			r.Header.Set("Content-Type", "application/json")
+			r.ContentLength = tc.contentLength
+			assertError(t, serve(r), http.StatusRequestEntityTooLarge, "request_body_too_large")
+		})
+	}
+	if n := calls.Load(); n != 0 {
+		t.Errorf("ViaCEP calls = %d, want 0 for oversized bodies", n)
+	}
+
+	if rec := postTemperature(t, "/temperature", `{"cep": "01001000"}`); rec.Code != http.StatusOK {
+		t.Errorf("body within the limit: status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+	}
+}
+
+func TestTemperatureRequestPayloadValidation(t *testing.T) {
+	tests := []struct {
+		name, body, code string
+	}{
+		{"empty cep", `{"cep": ""}`, "missing_cep"},
+		{"null cep", `{"cep": null}`, "missing_cep"},
+		{"missing cep", `{}`, "missing_cep"},
+		{"extra field", `{"cep": "01001000", "country": "BR"}`, "unknown_field"},
+		{"cep not a string", `{"cep": 1001000}`, "invalid_request_body"},
+		{"not JSON", `cep=01001000`, "invalid_request_body"},
+		{"empty body", "", "invalid_request_body"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := setupWithViaCEP(t)
+
+			assertError(t, postTemperature(t, "/temperature", tc.body), http.StatusBadRequest, tc.code)
+			if n := calls.Load(); n != 0 {
+				t.Errorf("ViaCEP calls = %d, want 0", n)
+			}
+		})
+	}
+}
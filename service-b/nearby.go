@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Item da resposta do endpoint search.json da WeatherAPI
+type WeatherAPISearchResult struct {
+	Name   string  `json:"name"`
+	Region string  `json:"region"`
+	Lat    float64 `json:"lat"`
+	Lon    float64 `json:"lon"`
+}
+
+type NearbyTemperature struct {
+	City  string  `json:"city"`
+	TempC float64 `json:"temp_C"`
+}
+
+// parseNearby lê o parâmetro ?nearby=N, limitado a NEARBY_MAX
+func parseNearby(r *http.Request) (int, error) {
+	raw := r.URL.Query().Get("nearby")
+	if raw == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid nearby parameter")
+	}
+	return min(n, cfg.NearbyMax), nil
+}
+
+// weatherAPISearchURL devolve o endpoint search.json no mesmo endereço de
+// WEATHER_API_URL
+func weatherAPISearchURL() string {
+	return originOf(cfg.WeatherAPIURL) + "/v1/search.json"
+}
+
+// searchNearbyCities consulta a WeatherAPI por localidades próximas às
+// coordenadas da estação que informou o clima da cidade, excluindo a própria
+// cidade
+func searchNearbyCities(ctx context.Context, city string, station Coordinates, limit int) ([]WeatherAPISearchResult, error) {
+	tracer := otel.Tracer("service-b")
+	ctx, span := startPhase(ctx, tracer, "search-nearby-cities")
+	defer span.End()
+
+	setAttributes(span, attribute.String("city", city), attribute.Int("nearby.limit", limit))
+
+	breaker := breakers.get("weatherapi")
+	if !breaker.allow(ctx) {
+		setAttributes(span, attribute.Bool("circuit.open", true))
+		span.SetStatus(codes.Error, "Circuit open")
+		return nil, fmt.Errorf("WeatherAPI search: %w", errCircuitOpen)
+	}
+	ctx, cancel := context.WithTimeout(ctx, cfg.WeatherAPITimeout)
+	defer cancel()
+
+	key, keyIndex := weatherKeys.next()
+	setAttributes(span, attribute.Int("weatherapi.key_index", keyIndex))
+	query := fmt.Sprintf("%f,%f", station.Lat, station.Lon)
+	endpoint := fmt.Sprintf("%s?key=%s&q=%s", weatherAPISearchURL(), key, url.QueryEscape(query))
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		err = redactURLError(err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to create request")
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	start := time.Now()
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		err = redactURLError(err)
+		breaker.record(ctx, isRetryable(err, 0))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "API request failed")
+		logf(ctx, "WeatherAPI search request failed: %v", err)
+		return nil, fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	breaker.record(ctx, isRetryable(nil, resp.StatusCode))
+
+	setAttributes(span, attribute.Int("http.status_code", resp.StatusCode))
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		if weatherKeys.report(keyIndex, parseWeatherAPIError(body)) {
+			span.AddEvent("weather api key quota exhausted")
+		}
+		logf(ctx, "WeatherAPI search returned status %d", resp.StatusCode)
+		span.SetStatus(codes.Error, "API returned error")
+		return nil, fmt.Errorf("search API returned status %d", resp.StatusCode)
+	}
+
+	var results []WeatherAPISearchResult
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode response")
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	var nearby []WeatherAPISearchResult
+	for _, result := range results {
+		if len(nearby) == limit {
+			break
+		}
+		if !sameCity(result.Name, city) && (result.Lat != station.Lat || result.Lon != station.Lon) {
+			nearby = append(nearby, result)
+		}
+	}
+	setAttributes(span, attribute.Int("nearby.found", len(nearby)))
+	logUpstreamSuccess(ctx, "weatherapi-search", resp.StatusCode, time.Since(start))
+	return nearby, nil
+}
+
+// sameCity compara nomes de cidades ignorando maiúsculas e acentos: a
+// WeatherAPI grafa "Sao Paulo" onde o CEP traz "São Paulo"
+func sameCity(a, b string) bool {
+	return strings.EqualFold(asciiFolder.Replace(a), asciiFolder.Replace(b))
+}
+
+// fetchNearbyTemperatures busca em paralelo a temperatura de até limit
+// localidades próximas à estação da cidade. Localidades cuja consulta falha
+// são omitidas do resultado e reportadas no erro devolvido junto com as
+// demais.
+func fetchNearbyTemperatures(ctx context.Context, city string, station Coordinates, limit int) ([]NearbyTemperature, error) {
+	// A busca de cidades próximas usa a API de busca da WeatherAPI
+	if weatherKeys == nil {
+		return nil, fmt.Errorf("nearby search: %w", errProviderUnsupported)
+	}
+	cities, err := searchNearbyCities(ctx, city, station, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*NearbyTemperature, len(cities))
+	errs := make([]error, len(cities))
+	var wg sync.WaitGroup
+	for i, c := range cities {
+		wg.Add(1)
+		go func(i int, c WeatherAPISearchResult) {
+			defer wg.Done()
+			weather, err := weatherProvider.Temperature(ctx, fmt.Sprintf("%f,%f", c.Lat, c.Lon))
+			if err != nil {
+				logf(ctx, "failed to fetch temperature for nearby city %s: %v", c.Name, err)
+				errs[i] = fmt.Errorf("%s: %w", c.Name, err)
+				return
+			}
+			results[i] = &NearbyTemperature{City: c.Name, TempC: roundTemperature(weather.TempC)}
+		}(i, c)
+	}
+	wg.Wait()
+
+	nearby := []NearbyTemperature{}
+	for _, result := range results {
+		if result != nil {
+			nearby = append(nearby, *result)
+		}
+	}
+	return nearby, errors.Join(errs...)
+}
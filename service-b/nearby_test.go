@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+)
+
+// Resposta de /v1/search.json em torno da estação de São Paulo: a primeira
+// localidade é a própria cidade e deve ser descartada
+const nearbySearchResults = `[
+	{"id": 1, "name": "Sao Paulo", "region": "Sao Paulo", "country": "Brazil", "lat": -23.53, "lon": -46.62},
+	{"id": 2, "name": "Guarulhos", "region": "Sao Paulo", "country": "Brazil", "lat": -23.47, "lon": -46.53},
+	{"id": 3, "name": "Osasco", "region": "Sao Paulo", "country": "Brazil", "lat": -23.53, "lon": -46.79},
+	{"id": 4, "name": "Santo Andre", "region": "Sao Paulo", "country": "Brazil", "lat": -23.67, "lon": -46.53},
+	{"id": 5, "name": "Diadema", "region": "Sao Paulo", "country": "Brazil", "lat": -23.69, "lon": -46.62}
+]`
+
+func TestTemperatureReturnsNearbyEntries(t *testing.T) {
+	current, err := os.ReadFile("testdata/weatherapi_current.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		mu            sync.Mutex
+		searchQueries []string
+	)
+	setupWithWeatherAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("key") != "test-key" {
+			t.Errorf("request %s without the API key", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/search.json":
+			mu.Lock()
+			searchQueries = append(searchQueries, r.URL.Query().Get("q"))
+			mu.Unlock()
+			w.Write([]byte(nearbySearchResults))
+		case "/v1/current.json":
+			w.Write(current)
+		default:
+			http.NotFound(w, r)
+		}
+	}, "NEARBY_MAX", "3")
+
+	for _, tc := range []struct {
+		nearby string
+		want   []string
+	}{
+		{"2", []string{"Guarulhos", "Osasco"}},
+		{"3", []string{"Guarulhos", "Osasco", "Santo Andre"}},
+		// Limitado por NEARBY_MAX
+		{"10", []string{"Guarulhos", "Osasco", "Santo Andre"}},
+	} {
+		t.Run(tc.nearby, func(t *testing.T) {
+			rec := getTemperature(t, "/temperature/01001000?nearby="+tc.nearby)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+			}
+			assertTemperatureSchema(t, rec)
+			got := decodeBody[TemperatureResponse](t, rec)
+			if got.Partial {
+				t.Errorf("partial response: %v", got.Warnings)
+			}
+			if len(got.Nearby) != len(tc.want) {
+				out, _ := json.Marshal(got.Nearby)
+				t.Fatalf("nearby = %s, want %d entries", out, len(tc.want))
+			}
+			for i, city := range tc.want {
+				if got.Nearby[i].City != city || got.Nearby[i].TempC != 22.1 {
+					t.Errorf("nearby[%d] = %+v, want %s at 22.1", i, got.Nearby[i], city)
+				}
+			}
+		})
+	}
+
+	// A busca é feita pelas coordenadas da estação, não pelo nome da cidade
+	mu.Lock()
+	defer mu.Unlock()
+	for _, q := range searchQueries {
+		if q != "-23.533300,-46.616700" {
+			t.Errorf("search q = %q, want the station coordinates", q)
+		}
+	}
+}
+
+func TestNearbyUnsupportedByOtherProviders(t *testing.T) {
+	setupWithViaCEP(t)
+
+	got := decodeBody[TemperatureResponse](t, getTemperature(t, "/temperature/01001000?nearby=2"))
+	if !got.Partial || len(got.Nearby) != 0 {
+		t.Errorf("partial/nearby = %v/%v, want a partial response without nearby entries", got.Partial, got.Nearby)
+	}
+}
+
+func TestNearbyFailureReturnsPartialResponse(t *testing.T) {
+	current, err := os.ReadFile("testdata/weatherapi_current.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	setupWithWeatherAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/search.json" {
+			http.Error(w, `{"error":{"code":9999,"message":"Internal application error."}}`, http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(current)
+	})
+
+	rec := getTemperature(t, "/temperature/01001000?nearby=2")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 with the core data (body %s)", rec.Code, rec.Body.String())
+	}
+	assertTemperatureSchema(t, rec)
+	got := decodeBody[TemperatureResponse](t, rec)
+	if got.TempC == nil || *got.TempC != 22.1 || got.City != "São Paulo" {
+		t.Errorf("core data = %v/%q, want 22.1 in São Paulo", got.TempC, got.City)
+	}
+	if !got.Partial || len(got.Warnings) != 1 || got.Warnings[0] != "nearby temperatures incomplete" {
+		t.Errorf("partial/warnings = %v/%q, want true/[nearby temperatures incomplete]", got.Partial, got.Warnings)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"time"
+)
+
+// notFoundCache guarda por NEGATIVE_CACHE_TTL os CEPs que o provedor informou
+// não existir, para que consultas repetidas a um CEP inválido não cheguem ao
+// ViaCEP
+type notFoundCache struct {
+	ttl     time.Duration
+	entries *shardedMap[time.Time]
+}
+
+var negativeCache *notFoundCache
+
+func newNotFoundCache(ttl time.Duration, shards int) *notFoundCache {
+	return &notFoundCache{
+		ttl:     ttl,
+		entries: newShardedMap[time.Time](shards),
+	}
+}
+
+func (c *notFoundCache) has(cep string) bool {
+	if c.ttl <= 0 {
+		return false
+	}
+
+	var found bool
+	c.entries.withShard(cep, func(entries map[string]time.Time) {
+		expiry, ok := entries[cep]
+		if ok && time.Now().After(expiry) {
+			delete(entries, cep)
+			return
+		}
+		found = ok
+	})
+	return found
+}
+
+func (c *notFoundCache) add(cep string) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.entries.withShard(cep, func(entries map[string]time.Time) {
+		now := time.Now()
+		// Descarta entradas vencidas para que o segmento não cresça indefinidamente
+		if len(entries) >= 256 {
+			for k, expiry := range entries {
+				if now.After(expiry) {
+					delete(entries, k)
+				}
+			}
+		}
+		entries[cep] = now.Add(c.ttl)
+	})
+}
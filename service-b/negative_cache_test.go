@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNotFoundCacheExpires(t *testing.T) {
+	c := newNotFoundCache(30*time.Millisecond, 4)
+	c.add("99999999")
+	if !c.has("99999999") {
+		t.Fatal("has = false right after add")
+	}
+	if c.has("88888888") {
+		t.Error("has = true for a CEP never added")
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	if c.has("99999999") {
+		t.Error("has = true after the TTL")
+	}
+}
+
+func TestSecondNotFoundServedFromCache(t *testing.T) {
+	for _, tc := range []struct {
+		ttl       string
+		wantCalls int32
+	}{
+		{"60s", 1},
+		{"0s", 2},
+	} {
+		t.Run("NEGATIVE_CACHE_TTL="+tc.ttl, func(t *testing.T) {
+			calls := setupWithViaCEP(t, "NEGATIVE_CACHE_TTL", tc.ttl)
+
+			for i := 0; i < 2; i++ {
+				assertError(t, getTemperature(t, "/temperature/99999999"), http.StatusNotFound, "zipcode_not_found")
+			}
+			if got := calls.Load(); got != tc.wantCalls {
+				t.Errorf("ViaCEP calls = %d, want %d", got, tc.wantCalls)
+			}
+		})
+	}
+}
+
+func TestNotFoundCacheKeepsFoundCEPsOut(t *testing.T) {
+	calls := setupWithViaCEP(t, "CEP_CACHE_TTL", "0s")
+
+	for i := 0; i < 2; i++ {
+		if rec := getTemperature(t, "/temperature/01001000"); rec.Code != http.StatusOK {
+			t.Fatalf("status = %d (body %s)", rec.Code, rec.Body.String())
+		}
+	}
+	// Sem cache de endereços, o CEP existente volta ao ViaCEP
+	if got := calls.Load(); got != 2 {
+		t.Errorf("ViaCEP calls = %d, want 2", got)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+const openWeatherMapURL = "https://api.openweathermap.org/data/2.5/weather"
+
+// Erros da OpenWeatherMap convertidos para as mesmas respostas dos códigos
+// equivalentes da WeatherAPI (ver weatherErrorStatus)
+var (
+	errWeatherCityNotFound = errors.New("weather city not found")
+	errWeatherAuth         = errors.New("weather api key rejected")
+)
+
+// OpenWeatherMapResponse traz os campos usados da resposta de
+// /data/2.5/weather com units=metric
+type OpenWeatherMapResponse struct {
+	Coord struct {
+		Lat float64 `json:"lat"`
+		Lon float64 `json:"lon"`
+	} `json:"coord"`
+	Weather []struct {
+		Description string `json:"description"`
+	} `json:"weather"`
+	Main struct {
+		// Ponteiro para distinguir um campo ausente de uma temperatura de 0°C
+		Temp     *float64 `json:"temp"`
+		Pressure float64  `json:"pressure"`
+		Humidity float64  `json:"humidity"`
+	} `json:"main"`
+	Wind struct {
+		Speed *float64 `json:"speed"` // m/s
+	} `json:"wind"`
+	Dt       int64  `json:"dt"`
+	Timezone int    `json:"timezone"` // deslocamento do UTC, em segundos
+	Name     string `json:"name"`
+}
+
+type openWeatherMapProvider struct{}
+
+func (openWeatherMapProvider) Name() string { return "openweathermap" }
+
+// Temperature consulta o clima atual da cidade na OpenWeatherMap e o converte
+// para Weather, com as mesmas proteções da WeatherAPI: throttle, circuitBreaker
+// e novas tentativas (WEATHERAPI_RETRY_*)
+func (p openWeatherMapProvider) Temperature(ctx context.Context, city string) (Weather, error) {
+	tracer := otel.Tracer("service-b")
+	ctx, span := startPhase(ctx, tracer, "fetch-temperature")
+	defer span.End()
+
+	setAttributes(span,
+		attribute.String("city", city),
+		attribute.String("weather.api", "openweathermap.org"),
+	)
+
+	if weather, ok := throttle.recent(p.Name(), city); ok {
+		setAttributes(span, attribute.Bool("upstream.throttled", true))
+		recordWeatherSource(ctx, p.Name(), true)
+		return weather.(Weather), nil
+	}
+
+	breaker := breakers.get(p.Name())
+	if !breaker.allow(ctx) {
+		setAttributes(span, attribute.Bool("circuit.open", true))
+		span.SetStatus(codes.Error, "Circuit open")
+		return Weather{}, fmt.Errorf("OpenWeatherMap: %w", errCircuitOpen)
+	}
+
+	// As cidades vêm do CEP, então a busca é restrita ao Brasil
+	query := url.Values{"q": {city + ",BR"}, "units": {"metric"}}
+	setAttributes(span, attribute.String("api.url", cfg.OpenWeatherMapURL+"?"+query.Encode()))
+	// A chave fica fora do atributo api.url; nos erros de transporte ela é
+	// mascarada por redactURLError
+	query.Set("appid", cfg.OpenWeatherMapAPIKey)
+
+	start := time.Now()
+	resp, err := retryGet(ctx, cfg.OpenWeatherMapURL+"?"+query.Encode(), retryPolicy{
+		provider:    p.Name(),
+		maxAttempts: cfg.WeatherAPIRetryMaxAttempts,
+		baseDelay:   cfg.WeatherAPIRetryBaseDelay,
+		timeout:     cfg.OpenWeatherMapTimeout,
+	}, breaker)
+	if err != nil {
+		logf(ctx, "OpenWeatherMap request failed: %v", err)
+		setAttributes(span, attribute.Bool("error.retryable", isRetryable(err, 0)))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "API request failed")
+		return Weather{}, fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	setAttributes(span, attribute.Int("http.status_code", resp.StatusCode))
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		logf(ctx, "OpenWeatherMap returned status %d: %s", resp.StatusCode, body)
+		var apiErr error
+		switch resp.StatusCode {
+		case http.StatusNotFound:
+			apiErr = errWeatherCityNotFound
+		case http.StatusUnauthorized:
+			apiErr = errWeatherAuth
+		default:
+			apiErr = fmt.Errorf("API error: status %d", resp.StatusCode)
+		}
+		setAttributes(span, attribute.Bool("error.retryable", isRetryable(nil, resp.StatusCode)))
+		span.RecordError(apiErr)
+		span.SetStatus(codes.Error, "API returned error")
+		return Weather{}, apiErr
+	}
+
+	var owmResp OpenWeatherMapResponse
+	if err := json.NewDecoder(resp.Body).Decode(&owmResp); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode response")
+		return Weather{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if owmResp.Main.Temp == nil {
+		span.SetStatus(codes.Error, "Invalid temperature data")
+		return Weather{}, errNoTemperature
+	}
+
+	weather := Weather{
+		TempC:      *owmResp.Main.Temp,
+		Humidity:   owmResp.Main.Humidity,
+		PressureMb: owmResp.Main.Pressure, // hPa equivale a mb
+		Station:    Coordinates{Lat: owmResp.Coord.Lat, Lon: owmResp.Coord.Lon},
+	}
+	if len(owmResp.Weather) > 0 {
+		weather.Condition = owmResp.Weather[0].Description
+	}
+	if owmResp.Wind.Speed != nil {
+		windKph := math.Round(*owmResp.Wind.Speed*3.6*10) / 10
+		weather.WindKph = &windKph
+	}
+	if owmResp.Dt > 0 {
+		weather.LastUpdated = time.Unix(owmResp.Dt, 0)
+		weather.LocalTime = weather.LastUpdated.In(time.FixedZone("", owmResp.Timezone))
+	}
+
+	setAttributes(span,
+		attribute.Float64("temperature.c", weather.TempC),
+		attribute.String("location", owmResp.Name),
+		attribute.Float64("weather.humidity", weather.Humidity),
+		attribute.String("weather.condition", weather.Condition),
+	)
+
+	throttle.record(p.Name(), city, weather)
+	recordWeatherSource(ctx, p.Name(), false)
+	logUpstreamSuccess(ctx, p.Name(), resp.StatusCode, time.Since(start))
+	return weather, nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newOpenWeatherMapStub sobe uma OpenWeatherMap falsa que responde status e
+// body a /data/2.5/weather, conferindo os parâmetros enviados
+func newOpenWeatherMapStub(t *testing.T, status int, body string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path != "/data/2.5/weather" || query.Get("q") != "Recife,BR" || query.Get("units") != "metric" || query.Get("appid") != "owm-key" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func setupOpenWeatherMap(t *testing.T, url string) {
+	t.Helper()
+	setupTest(t,
+		"WEATHER_PROVIDER", "openweathermap",
+		"OPENWEATHERMAP_API_KEY", "owm-key",
+		"OPENWEATHERMAP_URL", url,
+	)
+}
+
+func TestOpenWeatherMapTemperature(t *testing.T) {
+	fixture, err := os.ReadFile("testdata/openweathermap_weather.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newOpenWeatherMapStub(t, http.StatusOK, string(fixture))
+	setupOpenWeatherMap(t, srv.URL+"/data/2.5/weather")
+
+	weather, err := weatherProvider.Temperature(context.Background(), "Recife")
+	if err != nil {
+		t.Fatalf("Temperature: %v", err)
+	}
+	if weather.TempC != 28.03 {
+		t.Errorf("TempC = %v, want 28.03", weather.TempC)
+	}
+	if weather.Humidity != 65 || weather.PressureMb != 1012 {
+		t.Errorf("Humidity/PressureMb = %v/%v, want 65/1012", weather.Humidity, weather.PressureMb)
+	}
+	if weather.Condition != "nublado" {
+		t.Errorf("Condition = %q, want nublado", weather.Condition)
+	}
+	// 5.14 m/s * 3.6 = 18.504 km/h
+	if weather.WindKph == nil || *weather.WindKph != 18.5 {
+		t.Errorf("WindKph = %v, want 18.5", weather.WindKph)
+	}
+	if want := time.Date(2025, 10, 15, 15, 0, 0, 0, time.UTC); !weather.LastUpdated.Equal(want) {
+		t.Errorf("LastUpdated = %v, want %v", weather.LastUpdated, want)
+	}
+	// timezone -10800: horário de Brasília
+	if got := weather.LocalTime.Format("2006-01-02T15:04:05-07:00"); got != "2025-10-15T12:00:00-03:00" {
+		t.Errorf("LocalTime = %s, want 2025-10-15T12:00:00-03:00", got)
+	}
+	if weather.Station != (Coordinates{Lat: -8.0539, Lon: -34.8811}) {
+		t.Errorf("Station = %+v", weather.Station)
+	}
+}
+
+func TestOpenWeatherMapErrorMapping(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		status int
+		body   string
+		want   int
+		code   string
+	}{
+		{"city not found", http.StatusNotFound, `{"cod":"404","message":"city not found"}`, http.StatusNotFound, "weather_not_found"},
+		{"invalid key", http.StatusUnauthorized, `{"cod":401,"message":"Invalid API key. Please see https://openweathermap.org/faq#error401 for more info."}`, http.StatusBadGateway, "upstream_auth_error"},
+		{"server error", http.StatusBadGateway, `{"cod":"502","message":"Bad Gateway"}`, http.StatusInternalServerError, "weather_fetch_failed"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newOpenWeatherMapStub(t, tc.status, tc.body)
+			setupOpenWeatherMap(t, srv.URL+"/data/2.5/weather")
+
+			_, err := weatherProvider.Temperature(context.Background(), "Recife")
+			if err == nil {
+				t.Fatal("Temperature succeeded, want an error")
+			}
+			if m := weatherErrorStatus(err); m.status != tc.want || m.code != tc.code {
+				t.Errorf("weatherErrorStatus = %d %s, want %d %s", m.status, m.code, tc.want, tc.code)
+			}
+		})
+	}
+}
+
+func TestOpenWeatherMapDoesNotLeakAPIKey(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	setupOpenWeatherMap(t, srv.URL+"/data/2.5/weather")
+
+	_, err := weatherProvider.Temperature(context.Background(), "Recife")
+	if err == nil {
+		t.Fatal("Temperature succeeded against a closed server")
+	}
+	if strings.Contains(err.Error(), "owm-key") {
+		t.Errorf("error leaks the API key: %v", err)
+	}
+}
+
+func TestOpenWeatherMapTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	t.Cleanup(srv.Close)
+	// O prazo da WeatherAPI não vale para a OpenWeatherMap
+	setupTest(t,
+		"WEATHER_PROVIDER", "openweathermap",
+		"OPENWEATHERMAP_API_KEY", "owm-key",
+		"OPENWEATHERMAP_URL", srv.URL+"/data/2.5/weather",
+		"OPENWEATHERMAP_TIMEOUT", "20ms",
+		"WEATHERAPI_TIMEOUT", "5s",
+		"WEATHERAPI_RETRY_MAX_ATTEMPTS", "1",
+	)
+
+	start := time.Now()
+	if _, err := weatherProvider.Temperature(context.Background(), "Recife"); err == nil {
+		t.Fatal("Temperature succeeded against a stalled OpenWeatherMap")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Temperature took %s, want it cut at OPENWEATHERMAP_TIMEOUT", elapsed)
+	}
+}
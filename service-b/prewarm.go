@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// prewarmConnections abre conexões com os provedores externos na
+// inicialização, para que a primeira requisição real não pague o custo do
+// handshake. Falhas são apenas registradas em log.
+func prewarmConnections(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// Apenas os provedores em uso: os de CEP_PROVIDERS e o de clima, exceto o
+	// mock, que não faz chamadas de rede
+	var targets []string
+	for _, provider := range cfg.CEPProviders {
+		switch provider {
+		case "viacep":
+			targets = append(targets, originOf(cfg.ViaCEPURL)+"/")
+		case "brasilapi":
+			targets = append(targets, originOf(cfg.BrasilAPIURL)+"/")
+		}
+	}
+	switch weatherProvider.Name() {
+	case "weatherapi":
+		targets = append(targets, originOf(cfg.WeatherAPIURL)+"/")
+	case "openweathermap":
+		targets = append(targets, originOf(cfg.OpenWeatherMapURL)+"/")
+	}
+
+	var wg sync.WaitGroup
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target string) {
+			defer wg.Done()
+			start := time.Now()
+			req, err := http.NewRequestWithContext(ctx, http.MethodHead, target, nil)
+			if err != nil {
+				slog.Warn("Prewarm failed", "target", target, "error", err)
+				return
+			}
+			resp, err := httpClient.Do(req)
+			if err != nil {
+				slog.Warn("Prewarm failed", "target", target, "error", err)
+				return
+			}
+			// Consumir o corpo devolve a conexão ao pool
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			slog.Info("Prewarmed connection", "target", target, "duration_ms", time.Since(start).Milliseconds())
+		}(target)
+	}
+	wg.Wait()
+}
+
+// originOf devolve esquema e host de uma URL (ex.: "http://api.weatherapi.com")
+func originOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Scheme + "://" + u.Host
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// newPrewarmStub conta as requisições HEAD recebidas
+func newPrewarmStub(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var heads atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			heads.Add(1)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &heads
+}
+
+func TestPrewarmTargetsConfiguredWeatherProvider(t *testing.T) {
+	for _, tc := range []struct {
+		provider        string
+		weatherAPI, owm int32
+	}{
+		{"weatherapi", 1, 0},
+		{"openweathermap", 0, 1},
+		{"mock", 0, 0},
+	} {
+		t.Run(tc.provider, func(t *testing.T) {
+			viaCEP, viaCEPHeads := newPrewarmStub(t)
+			weatherAPI, weatherAPIHeads := newPrewarmStub(t)
+			owm, owmHeads := newPrewarmStub(t)
+			setupTest(t,
+				"WEATHER_PROVIDER", tc.provider,
+				"OPENWEATHERMAP_API_KEY", "owm-key",
+				"VIACEP_URL", viaCEP.URL,
+				"WEATHER_API_URL", weatherAPI.URL+"/v1/current.json",
+				"OPENWEATHERMAP_URL", owm.URL+"/data/2.5/weather",
+			)
+
+			prewarmConnections(context.Background())
+
+			if got := viaCEPHeads.Load(); got != 1 {
+				t.Errorf("ViaCEP HEAD requests = %d, want 1", got)
+			}
+			if got := weatherAPIHeads.Load(); got != tc.weatherAPI {
+				t.Errorf("WeatherAPI HEAD requests = %d, want %d", got, tc.weatherAPI)
+			}
+			if got := owmHeads.Load(); got != tc.owm {
+				t.Errorf("OpenWeatherMap HEAD requests = %d, want %d", got, tc.owm)
+			}
+		})
+	}
+}
+
+func TestPrewarmTargetsConfiguredCEPProviders(t *testing.T) {
+	for _, tc := range []struct {
+		providers         string
+		viaCEP, brasilAPI int32
+	}{
+		{"viacep", 1, 0},
+		{"brasilapi", 0, 1},
+		{"brasilapi,viacep", 1, 1},
+	} {
+		t.Run(tc.providers, func(t *testing.T) {
+			viaCEP, viaCEPHeads := newPrewarmStub(t)
+			brasilAPI, brasilAPIHeads := newPrewarmStub(t)
+			setupTest(t,
+				"CEP_PROVIDERS", tc.providers,
+				"VIACEP_URL", viaCEP.URL,
+				"BRASILAPI_URL", brasilAPI.URL,
+			)
+
+			prewarmConnections(context.Background())
+
+			if got := viaCEPHeads.Load(); got != tc.viaCEP {
+				t.Errorf("ViaCEP HEAD requests = %d, want %d", got, tc.viaCEP)
+			}
+			if got := brasilAPIHeads.Load(); got != tc.brasilAPI {
+				t.Errorf("BrasilAPI HEAD requests = %d, want %d", got, tc.brasilAPI)
+			}
+		})
+	}
+}
+
+func TestPrewarmFailureIsOnlyLogged(t *testing.T) {
+	viaCEP, viaCEPHeads := newPrewarmStub(t)
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	setupTest(t,
+		"WEATHER_PROVIDER", "weatherapi",
+		"VIACEP_URL", viaCEP.URL,
+		"WEATHER_API_URL", down.URL+"/v1/current.json",
+	)
+	logs := captureLogs(t)
+
+	prewarmConnections(context.Background())
+
+	if got := viaCEPHeads.Load(); got != 1 {
+		t.Errorf("ViaCEP HEAD requests = %d, want 1 despite the WeatherAPI failure", got)
+	}
+	failed := findLogs(logs(), "Prewarm failed")
+	if len(failed) != 1 || failed[0]["target"] != down.URL+"/" {
+		t.Errorf("prewarm failures logged = %v, want one for %s", failed, down.URL)
+	}
+}
@@ -0,0 +1,13 @@
+package main
+
+// Macrorregião do IBGE de cada UF
+var regionByUF = map[string]string{
+	"AC": "Norte", "AP": "Norte", "AM": "Norte", "PA": "Norte",
+	"RO": "Norte", "RR": "Norte", "TO": "Norte",
+	"AL": "Nordeste", "BA": "Nordeste", "CE": "Nordeste", "MA": "Nordeste",
+	"PB": "Nordeste", "PE": "Nordeste", "PI": "Nordeste", "RN": "Nordeste",
+	"SE": "Nordeste",
+	"DF": "Centro-Oeste", "GO": "Centro-Oeste", "MT": "Centro-Oeste", "MS": "Centro-Oeste",
+	"ES": "Sudeste", "MG": "Sudeste", "RJ": "Sudeste", "SP": "Sudeste",
+	"PR": "Sul", "RS": "Sul", "SC": "Sul",
+}
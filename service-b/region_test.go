@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestTemperatureRegion(t *testing.T) {
+	srv, _ := newViaCEPStub(t, map[string]string{
+		"01001000": saoPauloAddress,
+		"69005000": `{"cep": "69005-000", "localidade": "Manaus", "uf": "AM", "bairro": "Centro"}`,
+		"40010000": `{"cep": "40010-000", "localidade": "Salvador", "uf": "BA", "bairro": "Comércio"}`,
+		"70040000": `{"cep": "70040-000", "localidade": "Brasília", "uf": "DF", "bairro": "Asa Norte"}`,
+		"90010000": `{"cep": "90010-000", "localidade": "Porto Alegre", "uf": "RS", "bairro": "Centro Histórico"}`,
+	})
+	setupTest(t, "VIACEP_URL", srv.URL)
+
+	for cep, want := range map[string]string{
+		"01001000": "Sudeste",
+		"69005000": "Norte",
+		"40010000": "Nordeste",
+		"70040000": "Centro-Oeste",
+		"90010000": "Sul",
+	} {
+		rec := getTemperature(t, "/temperature/"+cep+"?extra=true")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d (body %s)", cep, rec.Code, rec.Body.String())
+		}
+		if got := decodeBody[map[string]any](t, rec)["region"]; got != want {
+			t.Errorf("%s: region = %v, want %q", cep, got, want)
+		}
+	}
+}
+
+func TestTemperatureRegionRequiresExtra(t *testing.T) {
+	setupWithViaCEP(t)
+
+	rec := getTemperature(t, "/temperature/01001000")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (body %s)", rec.Code, rec.Body.String())
+	}
+	if region, ok := decodeBody[map[string]any](t, rec)["region"]; ok {
+		t.Errorf("region = %v without extra=true, want it omitted", region)
+	}
+}
+
+func TestRegionByUFCoversAllStates(t *testing.T) {
+	if n := len(regionByUF); n != 27 {
+		t.Errorf("regionByUF has %d UFs, want 27", n)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// isRetryable indica se a falha de uma chamada a um provedor externo é
+// transitória: erros de rede, timeouts, 429 e 5xx. Os demais 4xx são
+// respostas definitivas e não devem ser repetidos.
+func isRetryable(err error, statusCode int) bool {
+	if err != nil {
+		// Cancelamento pelo cliente não é falha do provedor
+		if errors.Is(err, context.Canceled) {
+			return false
+		}
+		var netErr net.Error
+		if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return true
+		}
+	}
+
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// isTimeout indica se a chamada falhou por estourar o prazo
+func isTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// retryPolicy define quantas vezes e com que espera uma chamada é repetida
+type retryPolicy struct {
+	provider    string // rótulo das métricas
+	maxAttempts int
+	baseDelay   time.Duration
+	timeout     time.Duration // limite de cada tentativa
+
+	// classify decide se o resultado de uma tentativa deve ser repetido;
+	// quando nil, usa isRetryable com o status da resposta
+	classify func(resp *http.Response, err error) bool
+}
+
+// retryGet faz um GET idempotente repetindo as falhas transitórias (ver
+// isRetryable) com espera exponencial e jitter entre as tentativas. Cada
+// tentativa gera um span filho, é contabilizada no circuitBreaker do
+// provedor e registrada para o meta.attempts de ?debug=true. Com
+// RETRY_MAX_ELAPSED_MS, novas tentativas deixam de ser feitas quando a
+// próxima começaria depois do limite, mesmo que ainda restem tentativas; o
+// mesmo vale se o circuito abrir entre uma tentativa e outra, e então o erro
+// devolvido é errCircuitOpen. Senão devolve a resposta ou o erro da última
+// tentativa.
+func retryGet(ctx context.Context, target string, policy retryPolicy, breaker *circuitBreaker) (*http.Response, error) {
+	maxElapsed := time.Duration(cfg.RetryMaxElapsedMS) * time.Millisecond
+	begin := time.Now()
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		resp, err := getAttempt(ctx, target, attempt, policy.timeout)
+		recordAttempt(ctx, policy.provider)
+		status := 0
+		if err == nil {
+			status = resp.StatusCode
+		}
+		retryable := isRetryable(err, status)
+		if policy.classify != nil {
+			retryable = policy.classify(resp, err)
+		}
+		breaker.record(ctx, retryable)
+		recordUpstreamCall(policy.provider, status, err, time.Since(start))
+		if !retryable || attempt >= policy.maxAttempts {
+			return resp, err
+		}
+		delay := backoff(policy.baseDelay, attempt)
+		if maxElapsed > 0 && time.Since(begin)+delay >= maxElapsed {
+			trace.SpanFromContext(ctx).AddEvent("retry budget exhausted", trace.WithAttributes(
+				attribute.Int("retry.attempts", attempt),
+			))
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if !breaker.allow(ctx) {
+			trace.SpanFromContext(ctx).AddEvent("retry stopped by open circuit", trace.WithAttributes(
+				attribute.Int("retry.attempts", attempt),
+			))
+			return nil, fmt.Errorf("%s: %w", policy.provider, errCircuitOpen)
+		}
+	}
+}
+
+func getAttempt(ctx context.Context, target string, attempt int, timeout time.Duration) (*http.Response, error) {
+	tracer := otel.Tracer("service-b")
+	ctx, span := startPhase(ctx, tracer, "http-get-attempt")
+	defer span.End()
+
+	setAttributes(span, attribute.Int("retry.attempt", attempt))
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		cancel()
+		err = redactURLError(err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to create request")
+		return nil, err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		cancel()
+		err = redactURLError(err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Request failed")
+		return nil, err
+	}
+	setAttributes(span, attribute.Int("http.status_code", resp.StatusCode))
+	// O prazo da tentativa continua valendo até o corpo ser fechado
+	resp.Body = cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// Parâmetros de consulta que levam chaves de API (WeatherAPI e OpenWeatherMap)
+var secretQueryParams = []string{"key", "appid"}
+
+// redactURLError mascara as chaves de API da URL de um *url.Error: o
+// http.Client inclui a URL completa na mensagem, que acaba em logs e spans
+func redactURLError(err error) error {
+	urlErr, ok := err.(*url.Error)
+	if !ok {
+		return err
+	}
+	redacted := *urlErr
+	redacted.URL = redactURL(urlErr.URL)
+	return &redacted
+}
+
+// redactURL substitui o valor dos parâmetros de secretQueryParams por
+// "REDACTED"; uma URL que não pode ser interpretada perde toda a consulta
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		base, _, _ := strings.Cut(rawURL, "?")
+		return base
+	}
+	query := u.Query()
+	for _, name := range secretQueryParams {
+		if query.Has(name) {
+			query.Set(name, "REDACTED")
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// backoff devolve a espera antes da próxima tentativa: baseDelay dobrado a
+// cada tentativa, com jitter aleatório de até 50% para desencontrar clientes
+func backoff(baseDelay time.Duration, attempt int) time.Duration {
+	d := baseDelay << (attempt - 1)
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
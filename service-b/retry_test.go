@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestRedactURL(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{"https://api.weatherapi.com/v1/current.json?key=secret&q=S%C3%A3o+Paulo&aqi=no", "https://api.weatherapi.com/v1/current.json?aqi=no&key=REDACTED&q=S%C3%A3o+Paulo"},
+		{"https://api.openweathermap.org/data/2.5/weather?q=Recife%2CBR&appid=secret", "https://api.openweathermap.org/data/2.5/weather?appid=REDACTED&q=Recife%2CBR"},
+		{"https://viacep.com.br/ws/01001000/json/", "https://viacep.com.br/ws/01001000/json/"},
+		{"http://[::1%zz]/v1?key=secret", "http://[::1%zz]/v1"},
+	} {
+		if got := redactURL(tc.in); got != tc.want {
+			t.Errorf("redactURL(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestRetryGetRedactsAPIKey(t *testing.T) {
+	setupTest(t)
+	recorder := recordSpans(t)
+
+	// Servidor já encerrado: a conexão é recusada e o http.Client devolve um
+	// *url.Error com a URL completa
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	_, err := retryGet(context.Background(), srv.URL+"/v1/current.json?key=super-secret&q=Recife", retryPolicy{
+		provider:    "weatherapi",
+		maxAttempts: 2,
+		timeout:     cfg.WeatherAPITimeout,
+	}, breakers.get("weatherapi"))
+	if err == nil {
+		t.Fatal("retryGet succeeded against a closed server")
+	}
+	if strings.Contains(err.Error(), "super-secret") {
+		t.Errorf("error leaks the API key: %v", err)
+	}
+	if !strings.Contains(err.Error(), "key=REDACTED") {
+		t.Errorf("error = %v, want the redacted URL", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d attempt spans, want 2", len(spans))
+	}
+	for _, span := range spans {
+		for _, event := range span.Events() {
+			for _, attr := range event.Attributes {
+				if strings.Contains(attr.Value.Emit(), "super-secret") {
+					t.Errorf("span %s event %s leaks the API key: %s", span.Name(), event.Name, attr.Value.Emit())
+				}
+			}
+		}
+	}
+}
+
+func TestRetryGetStopsWhenCircuitOpens(t *testing.T) {
+	setupTest(t, "BREAKER_FAILURE_THRESHOLD", "1", "BREAKER_COOLDOWN", "1m")
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	_, err := retryGet(context.Background(), srv.URL, retryPolicy{
+		provider:    "viacep",
+		maxAttempts: 3,
+		baseDelay:   time.Millisecond,
+		timeout:     time.Second,
+	}, breakers.get("viacep"))
+	if !errors.Is(err, errCircuitOpen) {
+		t.Fatalf("err = %v, want errCircuitOpen", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("upstream received %d calls, want 1: the retry must not bypass the open circuit", n)
+	}
+}
+
+func TestIsRetryableStatusCodes(t *testing.T) {
+	for status, want := range map[int]bool{
+		http.StatusOK:                  false,
+		http.StatusBadRequest:          false,
+		http.StatusUnauthorized:        false,
+		http.StatusForbidden:           false,
+		http.StatusNotFound:            false,
+		http.StatusRequestTimeout:      false,
+		http.StatusUnprocessableEntity: false,
+		http.StatusTooManyRequests:     true,
+		http.StatusInternalServerError: true,
+		http.StatusBadGateway:          true,
+		http.StatusServiceUnavailable:  true,
+		http.StatusGatewayTimeout:      true,
+	} {
+		if got := isRetryable(nil, status); got != want {
+			t.Errorf("isRetryable(nil, %d) = %v, want %v", status, got, want)
+		}
+	}
+}
+
+func TestIsRetryableErrors(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"network", refused, true},
+		{"network in url.Error", &url.Error{Op: "Get", URL: "https://viacep.com.br", Err: refused}, true},
+		{"deadline", context.DeadlineExceeded, true},
+		{"wrapped deadline", fmt.Errorf("viacep: %w", context.DeadlineExceeded), true},
+		{"unexpected EOF", io.ErrUnexpectedEOF, true},
+		{"canceled", context.Canceled, false},
+		{"canceled in url.Error", &url.Error{Op: "Get", URL: "https://viacep.com.br", Err: context.Canceled}, false},
+		{"decode", errors.New("invalid character '<' looking for beginning of value"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err, 0); got != tt.want {
+				t.Errorf("isRetryable(%v, 0) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRetryableClientTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Timeout: 5 * time.Millisecond}
+	_, err := client.Get(srv.URL)
+	if err == nil || !isRetryable(err, 0) {
+		t.Errorf("isRetryable(%v, 0) = false, want true for a client timeout", err)
+	}
+}
+
+func TestSlowUpstreamTimesOut(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		env  []string
+	}{
+		{"client timeout", []string{"HTTP_CLIENT_TIMEOUT", "20ms"}},
+		{"attempt timeout", []string{"WEATHERAPI_TIMEOUT", "20ms"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			setupWithWeatherAPI(t, func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-time.After(time.Second):
+				case <-r.Context().Done():
+				}
+			}, append([]string{"WEATHERAPI_RETRY_MAX_ATTEMPTS", "1"}, tc.env...)...)
+
+			start := time.Now()
+			_, err := weatherProvider.Temperature(context.Background(), "São Paulo")
+			if !isTimeout(err) {
+				t.Fatalf("Temperature error = %v, want a timeout", err)
+			}
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("Temperature took %s, want it cut at the timeout", elapsed)
+			}
+		})
+	}
+}
+
+func TestSlowUpstreamRespectsContextDeadline(t *testing.T) {
+	setupWithWeatherAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}, "WEATHERAPI_RETRY_MAX_ATTEMPTS", "1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := weatherProvider.Temperature(ctx, "São Paulo"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Temperature error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestRetryGetStopsAtMaxElapsed(t *testing.T) {
+	tests := []struct {
+		maxElapsed string
+		wantCalls  int32
+	}{
+		// Cada tentativa leva 40ms: a terceira começaria depois dos 60ms
+		{"60", 2},
+		// Sem limite, valem apenas as tentativas
+		{"0", 4},
+	}
+	for _, tc := range tests {
+		t.Run("RETRY_MAX_ELAPSED_MS="+tc.maxElapsed, func(t *testing.T) {
+			setupTest(t, "RETRY_MAX_ELAPSED_MS", tc.maxElapsed, "BREAKER_FAILURE_THRESHOLD", "0")
+			recorder := recordSpans(t)
+
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				time.Sleep(40 * time.Millisecond)
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer srv.Close()
+
+			ctx, span := otel.Tracer("test").Start(context.Background(), "request")
+			resp, err := retryGet(ctx, srv.URL, retryPolicy{
+				provider:    "viacep",
+				maxAttempts: 4,
+				baseDelay:   time.Millisecond,
+				timeout:     time.Second,
+			}, breakers.get("viacep"))
+			span.End()
+			if err != nil {
+				t.Fatalf("retryGet: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusServiceUnavailable {
+				t.Errorf("status = %d, want the last attempt's 503", resp.StatusCode)
+			}
+			if n := calls.Load(); n != tc.wantCalls {
+				t.Errorf("upstream received %d calls, want %d", n, tc.wantCalls)
+			}
+
+			exhausted := slices.ContainsFunc(findSpan(t, recorder.Ended(), "request").Events(), func(e sdktrace.Event) bool {
+				return e.Name == "retry budget exhausted"
+			})
+			if want := tc.maxElapsed != "0"; exhausted != want {
+				t.Errorf("retry budget exhausted event = %v, want %v", exhausted, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// jsonSchema é o subconjunto do JSON Schema usado em testdata: type,
+// required, properties, additionalProperties, items e $ref para $defs
+type jsonSchema struct {
+	Ref                  string                 `json:"$ref"`
+	Type                 schemaTypes            `json:"type"`
+	Required             []string               `json:"required"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	AdditionalProperties json.RawMessage        `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	Defs                 map[string]*jsonSchema `json:"$defs"`
+}
+
+// schemaTypes aceita "type" como um nome ou uma lista de nomes
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*t = schemaTypes{one}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(t))
+}
+
+// allows indica se o tipo é aceito; como no JSON Schema, "number" inclui os
+// inteiros
+func (t schemaTypes) allows(typ string) bool {
+	if len(t) == 0 || slices.Contains(t, typ) {
+		return true
+	}
+	return typ == "integer" && slices.Contains(t, "number")
+}
+
+func loadSchema(t *testing.T, path string) *jsonSchema {
+	t.Helper()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema jsonSchema
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		t.Fatalf("invalid schema %s: %v", path, err)
+	}
+	return &schema
+}
+
+func loadTemperatureSchema(t *testing.T) *jsonSchema {
+	return loadSchema(t, "testdata/temperature_response.schema.json")
+}
+
+// validateSchema devolve as violações de schema encontradas no documento
+func validateSchema(schema *jsonSchema, document []byte) []string {
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return []string{fmt.Sprintf("invalid JSON: %v", err)}
+	}
+	var violations []string
+	schema.validate(schema, "$", value, &violations)
+	return violations
+}
+
+func (s *jsonSchema) validate(root *jsonSchema, path string, value any, violations *[]string) {
+	if s.Ref != "" {
+		name, ok := strings.CutPrefix(s.Ref, "#/$defs/")
+		def := root.Defs[name]
+		if !ok || def == nil {
+			*violations = append(*violations, fmt.Sprintf("%s: unresolved $ref %s", path, s.Ref))
+			return
+		}
+		def.validate(root, path, value, violations)
+		return
+	}
+
+	if !s.Type.allows(jsonType(value)) {
+		*violations = append(*violations, fmt.Sprintf("%s: type %s, want %s", path, jsonType(value), strings.Join(s.Type, " or ")))
+		return
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*violations = append(*violations, fmt.Sprintf("%s: missing required field %q", path, name))
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if prop, ok := s.Properties[name]; ok {
+				prop.validate(root, path+"."+name, v[name], violations)
+				continue
+			}
+			switch additional := bytes.TrimSpace(s.AdditionalProperties); {
+			case string(additional) == "false":
+				*violations = append(*violations, fmt.Sprintf("%s: unexpected field %q", path, name))
+			case len(additional) > 0 && string(additional) != "true":
+				var schema jsonSchema
+				if err := json.Unmarshal(additional, &schema); err != nil {
+					*violations = append(*violations, fmt.Sprintf("%s: invalid additionalProperties: %v", path, err))
+					continue
+				}
+				schema.validate(root, path+"."+name, v[name], violations)
+			}
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(root, fmt.Sprintf("%s[%d]", path, i), item, violations)
+			}
+		}
+	}
+}
+
+// jsonType devolve o tipo JSON Schema de um valor decodificado com UseNumber
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// assertTemperatureSchema falha o teste se o corpo da resposta não segue o
+// schema de TemperatureResponse
+func assertTemperatureSchema(t *testing.T, rec *httptest.ResponseRecorder) {
+	t.Helper()
+	for _, violation := range validateSchema(loadTemperatureSchema(t), rec.Body.Bytes()) {
+		t.Errorf("response does not match temperature_response.schema.json: %s\n%s", violation, rec.Body.String())
+	}
+}
+
+// jsonFields devolve os nomes JSON dos campos de uma struct e os que não têm
+// omitempty
+func jsonFields(typ reflect.Type) (all, required []string) {
+	for i := 0; i < typ.NumField(); i++ {
+		name, opts, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		all = append(all, name)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	sort.Strings(all)
+	sort.Strings(required)
+	return all, required
+}
+
+func TestTemperatureSchemaMatchesStruct(t *testing.T) {
+	schema := loadTemperatureSchema(t)
+
+	fields, required := jsonFields(reflect.TypeOf(TemperatureResponse{}))
+	properties := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		properties = append(properties, name)
+	}
+	sort.Strings(properties)
+	if !slices.Equal(fields, properties) {
+		t.Errorf("TemperatureResponse fields %v differ from schema properties %v: update testdata/temperature_response.schema.json (and schema_version) when the response changes", fields, properties)
+	}
+	schemaRequired := slices.Clone(schema.Required)
+	sort.Strings(schemaRequired)
+	if !slices.Equal(required, schemaRequired) {
+		t.Errorf("fields without omitempty %v differ from schema required %v", required, schemaRequired)
+	}
+
+	nearbyFields, _ := jsonFields(reflect.TypeOf(NearbyTemperature{}))
+	nearbyProperties := make([]string, 0)
+	for name := range schema.Properties["nearby"].Items.Properties {
+		nearbyProperties = append(nearbyProperties, name)
+	}
+	sort.Strings(nearbyProperties)
+	if !slices.Equal(nearbyFields, nearbyProperties) {
+		t.Errorf("NearbyTemperature fields %v differ from schema %v", nearbyFields, nearbyProperties)
+	}
+}
+
+func TestTemperatureSchemaRejectsRenamedOrRemovedFields(t *testing.T) {
+	schema := loadTemperatureSchema(t)
+	valid := `{"schema_version":"1.1","city":"São Paulo","temp_C":25,"temp_F":77,"temp_K":298.2,` +
+		`"nearby":[{"city":"Guarulhos","temp_C":24.5}],` +
+		`"sources":{"cep":{"provider":"viacep","cache_hit":false}},"meta":{"attempts":{"viacep":1}}}`
+	if v := validateSchema(schema, []byte(valid)); len(v) != 0 {
+		t.Fatalf("valid document rejected: %v", v)
+	}
+
+	for _, tc := range []struct {
+		name, old, new string
+	}{
+		{"required field removed", `"city":"São Paulo",`, ``},
+		{"schema_version removed", `"schema_version":"1.1",`, ``},
+		{"field renamed", `"temp_C":25`, `"tempC":25`},
+		{"nearby field renamed", `{"city":"Guarulhos","temp_C":24.5}`, `{"name":"Guarulhos","temp_C":24.5}`},
+		{"nearby field removed", `{"city":"Guarulhos","temp_C":24.5}`, `{"city":"Guarulhos"}`},
+		{"source field renamed", `"cache_hit":false`, `"cached":false`},
+		{"type changed", `"temp_C":25`, `"temp_C":"25"`},
+		{"attempts not integer", `"viacep":1`, `"viacep":1.5`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			document := strings.Replace(valid, tc.old, tc.new, 1)
+			if document == valid {
+				t.Fatalf("replacement %q not found", tc.old)
+			}
+			if v := validateSchema(schema, []byte(document)); len(v) == 0 {
+				t.Errorf("schema accepted %s", document)
+			}
+		})
+	}
+}
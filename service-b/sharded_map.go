@@ -0,0 +1,46 @@
+package main
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// shardedMap divide um mapa em segmentos com locks independentes, escolhidos
+// pelo hash da chave, para reduzir a disputa entre requisições concorrentes
+type shardedMap[V any] struct {
+	shards []mapShard[V]
+}
+
+type mapShard[V any] struct {
+	mu      sync.Mutex
+	entries map[string]V
+}
+
+func newShardedMap[V any](n int) *shardedMap[V] {
+	m := &shardedMap[V]{shards: make([]mapShard[V], n)}
+	for i := range m.shards {
+		m.shards[i].entries = make(map[string]V)
+	}
+	return m
+}
+
+// withShard executa fn com o segmento da chave bloqueado
+func (m *shardedMap[V]) withShard(key string, fn func(entries map[string]V)) {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	shard := &m.shards[h.Sum32()%uint32(len(m.shards))]
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	fn(shard.entries)
+}
+
+// forEachShard executa fn em cada segmento, um de cada vez, com o lock dele
+func (m *shardedMap[V]) forEachShard(fn func(entries map[string]V)) {
+	for i := range m.shards {
+		shard := &m.shards[i]
+		shard.mu.Lock()
+		fn(shard.entries)
+		shard.mu.Unlock()
+	}
+}
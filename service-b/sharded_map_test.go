@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShardedMapConcurrentAccess(t *testing.T) {
+	const workers, perWorker = 8, 500
+	m := newShardedMap[int](16)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				key := fmt.Sprintf("%d-%d", w, i)
+				m.withShard(key, func(entries map[string]int) { entries[key] = w*perWorker + i })
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for w := 0; w < workers; w++ {
+		for i := 0; i < perWorker; i++ {
+			key := fmt.Sprintf("%d-%d", w, i)
+			m.withShard(key, func(entries map[string]int) {
+				if got, ok := entries[key]; !ok || got != w*perWorker+i {
+					t.Errorf("entries[%s] = %d, %v, want %d", key, got, ok, w*perWorker+i)
+				}
+			})
+		}
+	}
+
+	// Todas as chaves aparecem exatamente uma vez, espalhadas pelos segmentos
+	total, used := 0, 0
+	m.forEachShard(func(entries map[string]int) {
+		total += len(entries)
+		if len(entries) > 0 {
+			used++
+		}
+	})
+	if total != workers*perWorker {
+		t.Errorf("total entries = %d, want %d", total, workers*perWorker)
+	}
+	if used != 16 {
+		t.Errorf("%d of 16 shards used, want keys spread over all of them", used)
+	}
+}
+
+func TestCEPCacheSharded(t *testing.T) {
+	for _, shards := range []int{1, 16} {
+		c := newCEPCache(time.Minute, shards)
+		c.set("01001000", Address{City: "São Paulo"})
+		if addr, ok := c.get("01001000"); !ok || addr.City != "São Paulo" {
+			t.Errorf("shards=%d: get = %+v, %v, want São Paulo", shards, addr, ok)
+		}
+		if _, ok := c.get("20040020"); ok {
+			t.Errorf("shards=%d: get hit for a CEP never set", shards)
+		}
+	}
+}
+
+// BenchmarkCEPCache compara um único lock (1 segmento) com o cache segmentado
+// sob acesso concorrente, com 90% de leituras
+func BenchmarkCEPCache(b *testing.B) {
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%08d", i)
+	}
+	for _, shards := range []int{1, 16, 64} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			c := newCEPCache(time.Hour, shards)
+			for _, k := range keys {
+				c.set(k, Address{City: "São Paulo"})
+			}
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					k := keys[i%len(keys)]
+					if i%10 == 0 {
+						c.set(k, Address{City: "São Paulo"})
+					} else {
+						c.get(k)
+					}
+					i++
+				}
+			})
+		})
+	}
+}
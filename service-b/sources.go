@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// Source identifica o provedor que forneceu um dado e se ele veio de cache
+type Source struct {
+	Provider string `json:"provider"`
+	CacheHit bool   `json:"cache_hit"`
+}
+
+// Sources descreve a origem dos dados da resposta, exibida com ?debug=true
+type Sources struct {
+	CEP     *Source `json:"cep,omitempty"`
+	Weather *Source `json:"weather,omitempty"`
+}
+
+// sourceRecorder acumula as origens dos dados ao longo de uma requisição.
+// Vale o primeiro registro de cada tipo, que corresponde ao dado principal.
+type sourceRecorder struct {
+	mu      sync.Mutex
+	sources Sources
+}
+
+type sourcesCtxKey struct{}
+
+func withSources(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sourcesCtxKey{}, &sourceRecorder{})
+}
+
+func recordCEPSource(ctx context.Context, provider string, cacheHit bool) {
+	if rec, ok := ctx.Value(sourcesCtxKey{}).(*sourceRecorder); ok {
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		if rec.sources.CEP == nil {
+			rec.sources.CEP = &Source{Provider: provider, CacheHit: cacheHit}
+		}
+	}
+}
+
+func recordWeatherSource(ctx context.Context, provider string, cacheHit bool) {
+	if rec, ok := ctx.Value(sourcesCtxKey{}).(*sourceRecorder); ok {
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		if rec.sources.Weather == nil {
+			rec.sources.Weather = &Source{Provider: provider, CacheHit: cacheHit}
+		}
+	}
+}
+
+func sourcesFrom(ctx context.Context) *Sources {
+	rec, ok := ctx.Value(sourcesCtxKey{}).(*sourceRecorder)
+	if !ok {
+		return nil
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	sources := rec.sources
+	return &sources
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func getSources(t *testing.T, target string) *Sources {
+	t.Helper()
+	rec := getTemperature(t, target)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+	}
+	return decodeBody[TemperatureResponse](t, rec).Sources
+}
+
+func TestDebugSourcesReflectProviders(t *testing.T) {
+	setupWithWeatherAPI(t, nil, "UPSTREAM_MIN_INTERVAL", "1m")
+
+	first := getSources(t, "/temperature/01001000?debug=true")
+	if first == nil || first.CEP == nil || first.Weather == nil {
+		t.Fatalf("first sources = %+v, want cep and weather", first)
+	}
+	if *first.CEP != (Source{"viacep", false}) || *first.Weather != (Source{"weatherapi", false}) {
+		t.Errorf("first sources = cep %+v, weather %+v, want viacep and weatherapi without cache", first.CEP, first.Weather)
+	}
+
+	// O endereço vem do cache de CEPs e o clima do throttle
+	second := getSources(t, "/temperature/01001000?debug=true")
+	if second == nil || second.CEP == nil || second.Weather == nil {
+		t.Fatalf("second sources = %+v, want cep and weather", second)
+	}
+	if *second.CEP != (Source{"viacep", true}) || *second.Weather != (Source{"weatherapi", true}) {
+		t.Errorf("second sources = cep %+v, weather %+v, want both cache hits", second.CEP, second.Weather)
+	}
+
+	if got := getSources(t, "/temperature/01001000"); got != nil {
+		t.Errorf("sources = %+v without debug=true", got)
+	}
+}
+
+func TestDebugSourcesNameMockProvider(t *testing.T) {
+	setupWithViaCEP(t)
+
+	got := getSources(t, "/temperature/01001000?debug=true")
+	if got == nil || got.Weather == nil || got.Weather.Provider != "mock" {
+		t.Errorf("sources = %+v, want the mock weather provider", got)
+	}
+}
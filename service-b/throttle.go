@@ -0,0 +1,66 @@
+package main
+
+import (
+	"time"
+)
+
+// upstreamThrottle impede chamadas idênticas ao mesmo provedor em intervalo
+// menor que o configurado, devolvendo o último resultado obtido com sucesso.
+// Protege a cota das APIs externas contra rajadas de requisições repetidas.
+type upstreamThrottle struct {
+	interval time.Duration
+	entries  *shardedMap[throttleEntry]
+}
+
+type throttleEntry struct {
+	at    time.Time
+	value any
+}
+
+var throttle *upstreamThrottle
+
+func newUpstreamThrottle(interval time.Duration, shards int) *upstreamThrottle {
+	return &upstreamThrottle{
+		interval: interval,
+		entries:  newShardedMap[throttleEntry](shards),
+	}
+}
+
+// recent devolve o último resultado de (provider, key) se ele foi obtido há
+// menos do que o intervalo mínimo
+func (t *upstreamThrottle) recent(provider, key string) (any, bool) {
+	if t.interval <= 0 {
+		return nil, false
+	}
+
+	var (
+		value any
+		found bool
+	)
+	t.entries.withShard(provider+"|"+key, func(entries map[string]throttleEntry) {
+		e, ok := entries[provider+"|"+key]
+		if ok && time.Since(e.at) < t.interval {
+			value, found = e.value, true
+		}
+	})
+	return value, found
+}
+
+func (t *upstreamThrottle) record(provider, key string, value any) {
+	if t.interval <= 0 {
+		return
+	}
+
+	t.entries.withShard(provider+"|"+key, func(entries map[string]throttleEntry) {
+		now := time.Now()
+		// Descarta entradas vencidas para que o segmento não cresça indefinidamente
+		if len(entries) >= 256 {
+			for k, e := range entries {
+				if now.Sub(e.at) >= t.interval {
+					delete(entries, k)
+				}
+			}
+		}
+		entries[provider+"|"+key] = throttleEntry{at: now, value: value}
+	})
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestUpstreamThrottle(t *testing.T) {
+	th := newUpstreamThrottle(50*time.Millisecond, 4)
+	th.record("weatherapi", "Recife", 28.0)
+
+	if v, ok := th.recent("weatherapi", "Recife"); !ok || v != 28.0 {
+		t.Errorf("recent = %v, %v, want the recorded value", v, ok)
+	}
+	// A chave inclui provedor e consulta
+	if _, ok := th.recent("openweathermap", "Recife"); ok {
+		t.Error("recent hit for another provider")
+	}
+	if _, ok := th.recent("weatherapi", "Olinda"); ok {
+		t.Error("recent hit for another city")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, ok := th.recent("weatherapi", "Recife"); ok {
+		t.Error("recent hit after the interval elapsed")
+	}
+}
+
+func TestRapidIdenticalCallIsThrottled(t *testing.T) {
+	for _, tc := range []struct {
+		interval  string
+		wantCalls int32
+	}{
+		{"0s", 2},
+		{"1m", 1},
+	} {
+		t.Run("UPSTREAM_MIN_INTERVAL="+tc.interval, func(t *testing.T) {
+			calls := setupWithWeatherAPI(t, nil, "UPSTREAM_MIN_INTERVAL", tc.interval)
+
+			for i := 0; i < 2; i++ {
+				if rec := getTemperature(t, "/temperature/01001000"); rec.Code != http.StatusOK {
+					t.Fatalf("status = %d (body %s)", rec.Code, rec.Body.String())
+				}
+			}
+			if calls.Load() != tc.wantCalls {
+				t.Errorf("WeatherAPI calls = %d, want %d", calls.Load(), tc.wantCalls)
+			}
+		})
+	}
+}
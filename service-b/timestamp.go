@@ -0,0 +1,17 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Timestamp é um horário da resposta, serializado conforme TIMESTAMP_FORMAT:
+// RFC3339 com o fuso original ou segundos desde a época Unix
+type Timestamp time.Time
+
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	if cfg.TimestampFormat == "epoch" {
+		return json.Marshal(time.Time(t).Unix())
+	}
+	return json.Marshal(time.Time(t).Format(time.RFC3339))
+}
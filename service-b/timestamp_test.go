@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// getExtraTimestamps consulta ?extra=true na WeatherAPI falsa e devolve
+// local_time, observed_at e served_at como vieram no JSON, além do intervalo
+// em que a requisição foi atendida
+func getExtraTimestamps(t *testing.T, format string) (fields map[string]json.RawMessage, before, after time.Time) {
+	t.Helper()
+	setupWithWeatherAPI(t, nil, "TIMESTAMP_FORMAT", format)
+
+	before = time.Now()
+	rec := getTemperature(t, "/temperature/01001000?extra=true")
+	after = time.Now()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+	}
+	assertTemperatureSchema(t, rec)
+	fields = decodeBody[map[string]json.RawMessage](t, rec)
+	for _, name := range []string{"local_time", "observed_at", "served_at"} {
+		if _, ok := fields[name]; !ok {
+			t.Fatalf("response lacks %s: %s", name, rec.Body.String())
+		}
+	}
+	return fields, before, after
+}
+
+func TestTimestampsRFC3339(t *testing.T) {
+	fields, before, after := getExtraTimestamps(t, "rfc3339")
+
+	if got := string(fields["local_time"]); got != `"2025-10-15T12:00:00-03:00"` {
+		t.Errorf("local_time = %s, want \"2025-10-15T12:00:00-03:00\"", got)
+	}
+	// last_updated_epoch 1760540100
+	if got := string(fields["observed_at"]); got != `"2025-10-15T14:55:00Z"` {
+		t.Errorf("observed_at = %s, want \"2025-10-15T14:55:00Z\"", got)
+	}
+	var raw string
+	if err := json.Unmarshal(fields["served_at"], &raw); err != nil {
+		t.Fatalf("served_at = %s, want a string", fields["served_at"])
+	}
+	servedAt, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		t.Fatalf("served_at %q is not RFC3339: %v", raw, err)
+	}
+	if servedAt.Before(before.Truncate(time.Second)) || servedAt.After(after) {
+		t.Errorf("served_at = %s, want between %s and %s", servedAt, before, after)
+	}
+}
+
+func TestTimestampsEpoch(t *testing.T) {
+	fields, before, after := getExtraTimestamps(t, "epoch")
+
+	if got := string(fields["local_time"]); got != "1760540400" {
+		t.Errorf("local_time = %s, want 1760540400", got)
+	}
+	if got := string(fields["observed_at"]); got != "1760540100" {
+		t.Errorf("observed_at = %s, want 1760540100", got)
+	}
+	var servedAt int64
+	if err := json.Unmarshal(fields["served_at"], &servedAt); err != nil {
+		t.Fatalf("served_at = %s, want an integer", fields["served_at"])
+	}
+	if servedAt < before.Unix() || servedAt > after.Unix() {
+		t.Errorf("served_at = %d, want between %d and %d", servedAt, before.Unix(), after.Unix())
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// phaseTimings acumula a duração de cada fase de uma requisição, para o log
+// de requisições lentas
+type phaseTimings struct {
+	mu     sync.Mutex
+	phases []string
+}
+
+type phaseTimingsCtxKey struct{}
+
+func withPhaseTimings(ctx context.Context) context.Context {
+	return context.WithValue(ctx, phaseTimingsCtxKey{}, &phaseTimings{})
+}
+
+func phaseTimingsFrom(ctx context.Context) *phaseTimings {
+	t, _ := ctx.Value(phaseTimingsCtxKey{}).(*phaseTimings)
+	return t
+}
+
+func (t *phaseTimings) add(name string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.phases = append(t.phases, fmt.Sprintf("%s=%v", name, d.Round(time.Millisecond)))
+}
+
+func (t *phaseTimings) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return strings.Join(t.phases, " ")
+}
+
+// timedSpan registra a duração da fase em phaseTimings ao ser encerrado
+type timedSpan struct {
+	trace.Span
+	name    string
+	start   time.Time
+	timings *phaseTimings
+}
+
+func (s timedSpan) End(options ...trace.SpanEndOption) {
+	s.timings.add(s.name, time.Since(s.start))
+	s.Span.End(options...)
+}
+
+// logSlowRequest registra um aviso com as durações das fases e o trace ID
+// quando a requisição passa de SLOW_REQUEST_THRESHOLD_MS
+func logSlowRequest(ctx context.Context, start time.Time) {
+	threshold := time.Duration(cfg.SlowRequestThresholdMS) * time.Millisecond
+	elapsed := time.Since(start)
+	if threshold <= 0 || elapsed <= threshold {
+		return
+	}
+
+	var phases string
+	if t := phaseTimingsFrom(ctx); t != nil {
+		phases = t.String()
+	}
+	slog.WarnContext(ctx, "slow request",
+		"total_ms", elapsed.Milliseconds(),
+		"phases", phases,
+	)
+}
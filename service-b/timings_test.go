@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlowRequestWarning(t *testing.T) {
+	recordSpans(t)
+	logs := captureLogs(t)
+	setupWithWeatherAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"location": {"name": "São Paulo"}, "current": {"temp_c": 25}}`))
+	}, "SLOW_REQUEST_THRESHOLD_MS", "10")
+
+	if rec := getTemperature(t, "/temperature/01001000"); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (body %s)", rec.Code, rec.Body.String())
+	}
+
+	slow := findLogs(logs(), "slow request")
+	if len(slow) != 1 {
+		t.Fatalf("got %d slow request warnings, want 1", len(slow))
+	}
+	entry := slow[0]
+	if entry["level"] != "WARN" {
+		t.Errorf("level = %v, want WARN", entry["level"])
+	}
+	if total, _ := entry["total_ms"].(float64); total < 30 {
+		t.Errorf("total_ms = %v, want at least the upstream delay", entry["total_ms"])
+	}
+	if id, _ := entry["trace_id"].(string); id == "" {
+		t.Error("slow request warning has no trace_id")
+	}
+	phases, _ := entry["phases"].(string)
+	for _, phase := range []string{"fetch-city-from-cep=", "fetch-temperature="} {
+		if !strings.Contains(phases, phase) {
+			t.Errorf("phases = %q, want it to include %s", phases, phase)
+		}
+	}
+}
+
+func TestFastRequestIsNotLoggedAsSlow(t *testing.T) {
+	logs := captureLogs(t)
+	setupWithViaCEP(t)
+
+	if rec := getTemperature(t, "/temperature/01001000"); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (body %s)", rec.Code, rec.Body.String())
+	}
+	if slow := findLogs(logs(), "slow request"); len(slow) != 0 {
+		t.Errorf("got %d slow request warnings under the default threshold, want 0", len(slow))
+	}
+}
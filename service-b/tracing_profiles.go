@@ -0,0 +1,47 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Perfis de tracing padrão, embutidos no binário
+//
+//go:embed tracing_profiles.json
+var defaultTracingProfiles []byte
+
+// tracingProfile define a taxa de amostragem e o endpoint do Zipkin de um
+// ambiente de implantação
+type tracingProfile struct {
+	SamplerRatio   float64 `json:"sampler_ratio"`
+	ZipkinEndpoint string  `json:"zipkin_endpoint"`
+}
+
+// loadTracingProfile seleciona o perfil do ambiente informado em DEPLOY_ENV,
+// lendo os perfis de TRACING_PROFILES_FILE quando definido ou dos perfis embutidos
+func loadTracingProfile(env, file string) (tracingProfile, error) {
+	data := defaultTracingProfiles
+	if file != "" {
+		var err error
+		data, err = os.ReadFile(file)
+		if err != nil {
+			return tracingProfile{}, fmt.Errorf("failed to read tracing profiles: %w", err)
+		}
+	}
+
+	var profiles map[string]tracingProfile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return tracingProfile{}, fmt.Errorf("failed to decode tracing profiles: %w", err)
+	}
+
+	profile, ok := profiles[env]
+	if !ok {
+		return tracingProfile{}, fmt.Errorf("no tracing profile for environment %q", env)
+	}
+	if profile.SamplerRatio < 0 || profile.SamplerRatio > 1 {
+		return tracingProfile{}, fmt.Errorf("invalid sampler ratio %v for environment %q", profile.SamplerRatio, env)
+	}
+	return profile, nil
+}
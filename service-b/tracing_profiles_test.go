@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadTracingProfileEmbedded(t *testing.T) {
+	for env, want := range map[string]float64{
+		"development": 1,
+		"staging":     0.5,
+		"production":  0.1,
+	} {
+		t.Run(env, func(t *testing.T) {
+			profile, err := loadTracingProfile(env, "")
+			if err != nil {
+				t.Fatalf("loadTracingProfile: %v", err)
+			}
+			if profile.SamplerRatio != want || profile.ZipkinEndpoint != "http://zipkin:9411/api/v2/spans" {
+				t.Errorf("profile = %+v, want sampler ratio %v and the compose Zipkin", profile, want)
+			}
+		})
+	}
+}
+
+func TestLoadTracingProfileFromFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "profiles.json")
+	err := os.WriteFile(file, []byte(`{
+		"staging": {"sampler_ratio": 0.25, "zipkin_endpoint": "http://zipkin.staging:9411/api/v2/spans"},
+		"production": {"sampler_ratio": 0.01, "zipkin_endpoint": "http://zipkin.prod:9411/api/v2/spans"}
+	}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		env      string
+		ratio    float64
+		endpoint string
+	}{
+		{"staging", 0.25, "http://zipkin.staging:9411/api/v2/spans"},
+		{"production", 0.01, "http://zipkin.prod:9411/api/v2/spans"},
+	}
+	for _, tt := range tests {
+		profile, err := loadTracingProfile(tt.env, file)
+		if err != nil {
+			t.Fatalf("%s: loadTracingProfile: %v", tt.env, err)
+		}
+		if profile.SamplerRatio != tt.ratio || profile.ZipkinEndpoint != tt.endpoint {
+			t.Errorf("%s: profile = %+v, want %v and %s", tt.env, profile, tt.ratio, tt.endpoint)
+		}
+	}
+
+	// O arquivo substitui os perfis embutidos
+	if _, err := loadTracingProfile("development", file); err == nil {
+		t.Error("development loaded from a file without it")
+	}
+}
+
+func TestLoadTracingProfileErrors(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "profiles.json")
+	if err := os.WriteFile(file, []byte(`{"qa": {"sampler_ratio": 1.5}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		env, file, want string
+	}{
+		{"sandbox", "", `no tracing profile for environment "sandbox"`},
+		{"qa", file, `invalid sampler ratio 1.5 for environment "qa"`},
+		{"qa", filepath.Join(t.TempDir(), "missing.json"), "failed to read tracing profiles"},
+	}
+	for _, tt := range tests {
+		if _, err := loadTracingProfile(tt.env, tt.file); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("loadTracingProfile(%q, %q) error = %v, want %q", tt.env, tt.file, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTemperatureUnits(t *testing.T) {
+	routes := []struct {
+		name string
+		do   func(t *testing.T, units string) *httptest.ResponseRecorder
+	}{
+		{"POST", func(t *testing.T, units string) *httptest.ResponseRecorder {
+			return postTemperature(t, "/temperature?units="+units, `{"cep": "01001000"}`)
+		}},
+		{"GET", func(t *testing.T, units string) *httptest.ResponseRecorder {
+			return getTemperature(t, "/temperature/01001000?units="+units)
+		}},
+	}
+	tests := []struct {
+		units string
+		want  []string
+	}{
+		{"c,f", []string{"temp_C", "temp_F"}},
+		{"k", []string{"temp_K"}},
+		{"F", []string{"temp_F"}},
+	}
+	for _, route := range routes {
+		for _, tt := range tests {
+			t.Run(route.name+" "+tt.units, func(t *testing.T) {
+				setupWithViaCEP(t)
+
+				rec := route.do(t, tt.units)
+				if rec.Code != http.StatusOK {
+					t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+				}
+				var got map[string]json.RawMessage
+				if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+					t.Fatal(err)
+				}
+				want := map[string]bool{}
+				for _, key := range tt.want {
+					want[key] = true
+				}
+				for _, key := range []string{"temp_C", "temp_F", "temp_K"} {
+					if _, ok := got[key]; ok != want[key] {
+						t.Errorf("%s present = %v, want %v (body %s)", key, ok, want[key], rec.Body.String())
+					}
+				}
+			})
+		}
+
+		t.Run(route.name+" unknown code", func(t *testing.T) {
+			calls := setupWithViaCEP(t)
+
+			assertError(t, route.do(t, "c,x"), http.StatusBadRequest, "invalid_units")
+			if n := calls.Load(); n != 0 {
+				t.Errorf("ViaCEP calls = %d, want 0", n)
+			}
+		})
+	}
+}
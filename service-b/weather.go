@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	_ "time/tzdata"
+)
+
+// Formato do campo location.localtime da WeatherAPI (ex.: "2025-03-16 14:05")
+const weatherAPILocalTimeLayout = "2006-01-02 15:04"
+
+// Weather reúne os dados meteorológicos obtidos para uma cidade
+type Weather struct {
+	TempC      float64
+	Humidity   float64
+	PressureMb float64
+	WindKph    *float64 // nil quando a WeatherAPI não informa
+	Condition  string
+	LocalTime  time.Time
+	Station    Coordinates // localidade a que a WeatherAPI associou a consulta
+	// Horário da observação na WeatherAPI; zero quando não informado
+	LastUpdated time.Time
+}
+
+func celsiusToFahrenheit(c float64) float64 {
+	return c*1.8 + 32
+}
+
+func celsiusToKelvin(c float64) float64 {
+	return c + 273.15
+}
+
+// Casas decimais das temperaturas nas respostas
+const temperatureDecimals = 1
+
+// Precision descreve o arredondamento aplicado às temperaturas e o erro máximo
+// que ele introduz, exibido com ?debug=true
+type Precision struct {
+	Decimals int     `json:"decimals"`
+	MaxError float64 `json:"max_error"`
+}
+
+func roundTemperature(v float64) float64 {
+	scale := math.Pow10(temperatureDecimals)
+	return math.Round(v*scale) / scale
+}
+
+// temperaturePrecision informa o limite do erro de arredondamento: metade da
+// última casa decimal mantida
+func temperaturePrecision() Precision {
+	return Precision{
+		Decimals: temperatureDecimals,
+		MaxError: 0.5 / math.Pow10(temperatureDecimals),
+	}
+}
+
+// dewPointC calcula o ponto de orvalho pela fórmula de Magnus, a partir da
+// temperatura em Celsius e da umidade relativa em porcentagem
+func dewPointC(tempC, humidity float64) float64 {
+	const b, c = 17.62, 243.12
+	gamma := math.Log(humidity/100) + b*tempC/(c+tempC)
+	return c * gamma / (b - gamma)
+}
+
+// parseLocalTime interpreta o horário local retornado pela WeatherAPI no fuso
+// horário da localidade (tz_id)
+func parseLocalTime(localtime, tzID string) (time.Time, error) {
+	loc, err := time.LoadLocation(tzID)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unknown time zone %q: %w", tzID, err)
+	}
+	t, err := time.ParseInLocation(weatherAPILocalTimeLayout, localtime, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid local time %q: %w", localtime, err)
+	}
+	return t, nil
+}
+
+// isWeatherSkipped indica se a cidade está em WEATHER_SKIP_CITIES, lista de
+// cidades sem dados de clima que não devem ser consultadas na WeatherAPI
+func isWeatherSkipped(city string) bool {
+	for _, skipped := range cfg.WeatherSkipCities {
+		if strings.EqualFold(skipped, city) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Corpo de erro da WeatherAPI: {"error":{"code":1006,"message":"..."}}
+type WeatherAPIErrorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// weatherAPIError representa um erro de negócio retornado pela WeatherAPI
+type weatherAPIError struct {
+	Code    int
+	Message string
+}
+
+func (e *weatherAPIError) Error() string {
+	return fmt.Sprintf("API error %d: %s", e.Code, e.Message)
+}
+
+type errorMapping struct {
+	status  int
+	message string
+	code    string
+}
+
+// Status HTTP devolvido ao cliente para cada código de erro da WeatherAPI.
+// Códigos ausentes do mapa resultam em 500.
+var weatherAPIErrorStatus = map[int]errorMapping{
+	// Problemas com a chave da API
+	1002: {http.StatusBadGateway, "upstream auth error", "upstream_auth_error"},
+	2006: {http.StatusBadGateway, "upstream auth error", "upstream_auth_error"},
+	2007: {http.StatusBadGateway, "upstream auth error", "upstream_auth_error"},
+	2008: {http.StatusBadGateway, "upstream auth error", "upstream_auth_error"},
+	// Nenhuma localidade encontrada para a cidade
+	1006: {http.StatusNotFound, "can not find weather for city", "weather_not_found"},
+	// Erro interno da WeatherAPI
+	9999: {http.StatusServiceUnavailable, "weather service unavailable", "weather_service_unavailable"},
+}
+
+// parseWeatherAPIError converte o corpo de uma resposta não-200 da WeatherAPI
+// em *weatherAPIError, ou em um erro genérico quando o corpo não segue o formato
+func parseWeatherAPIError(body []byte) error {
+	var apiResp WeatherAPIErrorResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil || apiResp.Error.Code == 0 {
+		return fmt.Errorf("API error: %s", string(body))
+	}
+	return &weatherAPIError{Code: apiResp.Error.Code, Message: apiResp.Error.Message}
+}
+
+// errNoTemperature indica que a WeatherAPI respondeu, mas sem temperatura para
+// a cidade
+var errNoTemperature = errors.New("no temperature data")
+
+// weatherErrorStatus define o status, a mensagem e o código da resposta para
+// um erro retornado por WeatherProvider.Temperature
+func weatherErrorStatus(err error) errorMapping {
+	if errors.Is(err, errCircuitOpen) {
+		return errorMapping{http.StatusServiceUnavailable, "weather service unavailable", "weather_service_unavailable"}
+	}
+	if errors.Is(err, errWeatherCityNotFound) {
+		return weatherAPIErrorStatus[1006]
+	}
+	if errors.Is(err, errWeatherAuth) {
+		return weatherAPIErrorStatus[2006]
+	}
+	var apiErr *weatherAPIError
+	if errors.As(err, &apiErr) {
+		if m, ok := weatherAPIErrorStatus[apiErr.Code]; ok {
+			return m
+		}
+	}
+	return errorMapping{http.StatusInternalServerError, "failed to fetch temperature", "weather_fetch_failed"}
+}
+
+// Códigos da WeatherAPI que indicam falha transitória. Os demais (chave
+// inválida, consulta longa demais (1007), localidade não encontrada...) são
+// definitivos e não devem ser repetidos.
+var weatherAPIRetryableCodes = map[int]bool{
+	9999: true,
+}
+
+// isRetryableWeatherError classifica um erro da WeatherAPI pelo código de
+// negócio, já que a API responde 400 tanto para erros definitivos quanto para
+// o erro interno 9999
+func isRetryableWeatherError(err error, statusCode int) bool {
+	var apiErr *weatherAPIError
+	if errors.As(err, &apiErr) {
+		return weatherAPIRetryableCodes[apiErr.Code]
+	}
+	return isRetryable(err, statusCode)
+}
+
+// weatherAPIRetryable é o classificador de retryGet para a WeatherAPI: lê o
+// corpo das respostas de erro para obter o código e o devolve intacto à resposta
+func weatherAPIRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return isRetryable(err, 0)
+	}
+	if resp.StatusCode == http.StatusOK {
+		return false
+	}
+
+	body, readErr := io.ReadAll(resp.Body)
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{bytes.NewReader(body), resp.Body}
+	if readErr != nil {
+		return isRetryable(readErr, resp.StatusCode)
+	}
+	return isRetryableWeatherError(parseWeatherAPIError(body), resp.StatusCode)
+}
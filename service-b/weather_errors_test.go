@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestWeatherAPIErrorCodeMapping(t *testing.T) {
+	tests := []struct {
+		apiCode    int
+		httpStatus int
+		wantStatus int
+		wantCode   string
+	}{
+		{1002, http.StatusUnauthorized, http.StatusBadGateway, "upstream_auth_error"},
+		{2006, http.StatusUnauthorized, http.StatusBadGateway, "upstream_auth_error"},
+		{2007, http.StatusForbidden, http.StatusBadGateway, "upstream_auth_error"},
+		{2008, http.StatusForbidden, http.StatusBadGateway, "upstream_auth_error"},
+		{1006, http.StatusBadRequest, http.StatusNotFound, "weather_not_found"},
+		{9999, http.StatusBadRequest, http.StatusServiceUnavailable, "weather_service_unavailable"},
+		// Código fora do mapa
+		{1003, http.StatusBadRequest, http.StatusInternalServerError, "weather_fetch_failed"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.apiCode), func(t *testing.T) {
+			setupWithWeatherAPI(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.httpStatus)
+				fmt.Fprintf(w, `{"error":{"code":%d,"message":"test error"}}`, tt.apiCode)
+			})
+
+			assertError(t, getTemperature(t, "/temperature/01001000"), tt.wantStatus, tt.wantCode)
+		})
+	}
+}
+
+func TestWeatherAPIUnparsableErrorIs500(t *testing.T) {
+	setupWithWeatherAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		io.WriteString(w, "<html>Bad Gateway</html>")
+	})
+
+	assertError(t, getTemperature(t, "/temperature/01001000"), http.StatusInternalServerError, "weather_fetch_failed")
+}
+
+func TestWeatherAPIErrorRetries(t *testing.T) {
+	tests := []struct {
+		apiCode   int
+		wantCalls int32
+	}{
+		// Consulta longa demais: erro definitivo do cliente
+		{1007, 1},
+		{1006, 1},
+		{1003, 1},
+		// Erro interno da WeatherAPI: transitório
+		{9999, 3},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.apiCode), func(t *testing.T) {
+			calls := setupWithWeatherAPI(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, `{"error":{"code":%d,"message":"test error"}}`, tt.apiCode)
+			}, "WEATHERAPI_RETRY_MAX_ATTEMPTS", "3")
+
+			getTemperature(t, "/temperature/01001000")
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("WeatherAPI calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestIsRetryableWeatherError(t *testing.T) {
+	for code, want := range map[int]bool{1002: false, 1006: false, 1007: false, 2008: false, 9999: true} {
+		err := &weatherAPIError{Code: code}
+		if got := isRetryableWeatherError(err, http.StatusBadRequest); got != want {
+			t.Errorf("isRetryableWeatherError(%d) = %v, want %v", code, got, want)
+		}
+	}
+	// Sem código de negócio vale a classificação pelo status HTTP
+	if !isRetryableWeatherError(nil, http.StatusServiceUnavailable) {
+		t.Error("isRetryableWeatherError(nil, 503) = false, want true")
+	}
+	if isRetryableWeatherError(nil, http.StatusBadRequest) {
+		t.Error("isRetryableWeatherError(nil, 400) = true, want false")
+	}
+}
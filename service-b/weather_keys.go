@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Código da WeatherAPI para chave que excedeu a cota mensal
+const weatherAPIQuotaExceeded = 2007
+
+// weatherKey é uma chave da WeatherAPI com seu peso na rotação
+type weatherKey struct {
+	value     string
+	weight    int
+	current   int
+	skipUntil time.Time
+}
+
+// weatherKeyPool distribui as consultas entre as chaves de WEATHER_API_KEYS
+// por round-robin ponderado suave (o mesmo do nginx): cada chave recebe uma
+// fração das consultas proporcional ao peso, sem rajadas seguidas. Chaves que
+// esgotaram a cota ficam de fora por WEATHER_API_KEY_COOLDOWN.
+type weatherKeyPool struct {
+	mu       sync.Mutex
+	keys     []*weatherKey
+	cooldown time.Duration
+}
+
+var weatherKeys *weatherKeyPool
+
+// parseWeatherKeys interpreta os itens de WEATHER_API_KEYS, no formato
+// "<chave>" ou "<chave>:<peso>"
+func parseWeatherKeys(entries []string) ([]*weatherKey, error) {
+	keys := make([]*weatherKey, 0, len(entries))
+	for i, entry := range entries {
+		value, rawWeight, hasWeight := strings.Cut(entry, ":")
+		weight := 1
+		if hasWeight {
+			n, err := strconv.Atoi(rawWeight)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid weight for weather api key at position %d", i+1)
+			}
+			weight = n
+		}
+		if value == "" {
+			return nil, fmt.Errorf("empty weather api key at position %d", i+1)
+		}
+		keys = append(keys, &weatherKey{value: value, weight: weight})
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no weather api key configured")
+	}
+	return keys, nil
+}
+
+func newWeatherKeyPool(keys []*weatherKey, cooldown time.Duration) *weatherKeyPool {
+	return &weatherKeyPool{keys: keys, cooldown: cooldown}
+}
+
+// next escolhe a chave da próxima consulta e devolve também sua posição, que
+// identifica a chave nos spans sem expô-la. Se todas estiverem com a cota
+// esgotada, a rotação segue entre todas: melhor tentar do que falhar direto.
+func (p *weatherKeyPool) next() (string, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	available := make([]int, 0, len(p.keys))
+	for i, k := range p.keys {
+		if !now.Before(k.skipUntil) {
+			available = append(available, i)
+		}
+	}
+	if len(available) == 0 {
+		for i := range p.keys {
+			available = append(available, i)
+		}
+	}
+
+	best, total := -1, 0
+	for _, i := range available {
+		k := p.keys[i]
+		k.current += k.weight
+		total += k.weight
+		if best < 0 || k.current > p.keys[best].current {
+			best = i
+		}
+	}
+	p.keys[best].current -= total
+	return p.keys[best].value, best
+}
+
+// report registra o erro de uma consulta feita com a chave da posição index;
+// cota esgotada tira a chave da rotação pelo tempo de espera
+func (p *weatherKeyPool) report(index int, err error) bool {
+	var apiErr *weatherAPIError
+	if !errors.As(err, &apiErr) || apiErr.Code != weatherAPIQuotaExceeded {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys[index].skipUntil = time.Now().Add(p.cooldown)
+	return true
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestKeyPool(t *testing.T, cooldown time.Duration, entries ...string) *weatherKeyPool {
+	t.Helper()
+	keys, err := parseWeatherKeys(entries)
+	if err != nil {
+		t.Fatalf("parseWeatherKeys: %v", err)
+	}
+	return newWeatherKeyPool(keys, cooldown)
+}
+
+// countKeys chama next n vezes e conta quantas vezes cada chave foi escolhida
+func countKeys(pool *weatherKeyPool, n int) map[string]int {
+	counts := make(map[string]int)
+	for range n {
+		key, _ := pool.next()
+		counts[key]++
+	}
+	return counts
+}
+
+func TestParseWeatherKeys(t *testing.T) {
+	keys, err := parseWeatherKeys([]string{"alpha:3", "beta"})
+	if err != nil {
+		t.Fatalf("parseWeatherKeys: %v", err)
+	}
+	if len(keys) != 2 || keys[0].value != "alpha" || keys[0].weight != 3 || keys[1].value != "beta" || keys[1].weight != 1 {
+		t.Errorf("keys = %+v, %+v", *keys[0], *keys[1])
+	}
+
+	for _, entries := range [][]string{{}, {""}, {":2"}, {"alpha:0"}, {"alpha:x"}, {"alpha", "beta:-1"}} {
+		if _, err := parseWeatherKeys(entries); err == nil {
+			t.Errorf("parseWeatherKeys(%q) succeeded, want an error", entries)
+		}
+	}
+}
+
+func TestWeatherKeysRotateByWeight(t *testing.T) {
+	pool := newTestKeyPool(t, time.Hour, "alpha:3", "beta:1")
+
+	// Round-robin suave: a chave de peso 1 não espera três seguidas da outra
+	var sequence []string
+	for range 4 {
+		key, _ := pool.next()
+		sequence = append(sequence, key)
+	}
+	if got := fmt.Sprint(sequence); got != "[alpha alpha beta alpha]" {
+		t.Errorf("sequence = %s, want [alpha alpha beta alpha]", got)
+	}
+
+	counts := countKeys(pool, 400)
+	if counts["alpha"] != 300 || counts["beta"] != 100 {
+		t.Errorf("counts = %v, want alpha 300 and beta 100", counts)
+	}
+}
+
+func TestWeatherKeyQuotaExceededIsSkippedForCooldown(t *testing.T) {
+	const cooldown = 50 * time.Millisecond
+	pool := newTestKeyPool(t, cooldown, "alpha", "beta")
+
+	_, index := pool.next()
+	if index != 0 {
+		t.Fatalf("first key index = %d, want 0", index)
+	}
+	// Outros erros não tiram a chave da rotação
+	if pool.report(index, &weatherAPIError{Code: 1006, Message: "No matching location found."}) {
+		t.Error("report(1006) = true, want false")
+	}
+	if !pool.report(index, &weatherAPIError{Code: weatherAPIQuotaExceeded, Message: "API key has exceeded calls per month quota."}) {
+		t.Fatal("report(2007) = false, want true")
+	}
+
+	if counts := countKeys(pool, 10); counts["beta"] != 10 {
+		t.Errorf("during the cooldown counts = %v, want only beta", counts)
+	}
+
+	time.Sleep(cooldown)
+	if counts := countKeys(pool, 10); counts["alpha"] == 0 {
+		t.Errorf("after the cooldown counts = %v, want alpha back in rotation", counts)
+	}
+}
+
+func TestWeatherKeysAllExhaustedKeepRotating(t *testing.T) {
+	pool := newTestKeyPool(t, time.Hour, "alpha", "beta")
+	quota := &weatherAPIError{Code: weatherAPIQuotaExceeded}
+	pool.report(0, quota)
+	pool.report(1, quota)
+
+	if counts := countKeys(pool, 10); counts["alpha"] != 5 || counts["beta"] != 5 {
+		t.Errorf("counts = %v, want the rotation to continue among all keys", counts)
+	}
+}
+
+func TestWeatherAPISkipsKeyAfterQuotaExceeded(t *testing.T) {
+	current, err := os.ReadFile("testdata/weatherapi_current.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		mu   sync.Mutex
+		used []string
+	)
+	setupWithWeatherAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Query().Get("key")
+		mu.Lock()
+		used = append(used, key)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if key == "exhausted" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error":{"code":2007,"message":"API key has exceeded calls per month quota."}}`))
+			return
+		}
+		w.Write(current)
+	}, "WEATHER_API_KEYS", "exhausted,spare")
+
+	assertError(t, getTemperature(t, "/temperature/01001000"), http.StatusBadGateway, "upstream_auth_error")
+	for range 3 {
+		if rec := getTemperature(t, "/temperature/01001000"); rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200 with the spare key (body %s)", rec.Code, rec.Body.String())
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := fmt.Sprint(used); got != "[exhausted spare spare spare]" {
+		t.Errorf("keys used = %s, want [exhausted spare spare spare]", got)
+	}
+}
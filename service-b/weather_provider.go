@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+// WeatherProvider consulta o clima atual de uma cidade em um provedor
+// externo. Os erros seguem o mapeamento de weatherErrorStatus.
+type WeatherProvider interface {
+	Name() string
+	Temperature(ctx context.Context, city string) (Weather, error)
+}
+
+// Provedor usado por respondTemperature, conforme WEATHER_PROVIDER
+var weatherProvider WeatherProvider
+
+// errProviderUnsupported indica um recurso que depende de uma API específica
+// da WeatherAPI, indisponível com o provedor configurado
+var errProviderUnsupported = errors.New("not supported by the configured weather provider")
+
+func newWeatherProvider(name string) WeatherProvider {
+	switch name {
+	case "openweathermap":
+		return openWeatherMapProvider{}
+	case "mock":
+		return mockWeatherProvider{weather: Weather{
+			TempC:      25,
+			Humidity:   60,
+			PressureMb: 1013,
+			Condition:  "Sunny",
+		}}
+	}
+	return weatherAPIProvider{}
+}
+
+type weatherAPIProvider struct{}
+
+func (weatherAPIProvider) Name() string { return "weatherapi" }
+
+// mockWeatherProvider responde sempre o mesmo clima (ou erro), sem chamadas de
+// rede: útil para rodar o serviço sem chave da WeatherAPI e para testes
+type mockWeatherProvider struct {
+	weather Weather
+	err     error
+}
+
+func (mockWeatherProvider) Name() string { return "mock" }
+
+func (p mockWeatherProvider) Temperature(ctx context.Context, city string) (Weather, error) {
+	if p.err != nil {
+		return Weather{}, p.err
+	}
+	recordWeatherSource(ctx, p.Name(), false)
+	return p.weather, nil
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDewPointC(t *testing.T) {
+	// Valores de referência das tabelas de ponto de orvalho
+	tests := []struct {
+		tempC, humidity, want float64
+	}{
+		{20, 50, 9.3},
+		{25, 60, 16.7},
+		{35, 30, 14.8},
+		{0, 80, -3.0},
+		{-10, 90, -11.3},
+		// Ar saturado: o ponto de orvalho é a própria temperatura
+		{30, 100, 30},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v°C/%v%%", tt.tempC, tt.humidity), func(t *testing.T) {
+			if got := roundTemperature(dewPointC(tt.tempC, tt.humidity)); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("dewPointC = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemperatureExtraPressureAndDewpoint(t *testing.T) {
+	setupWithWeatherAPI(t, nil)
+
+	rec := getTemperature(t, "/temperature/01001000?extra=true")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (body %s)", rec.Code, rec.Body.String())
+	}
+	got := decodeBody[map[string]any](t, rec)
+	// testdata/weatherapi_current.json: pressure_mb 1018, 22.1°C e 64% de umidade
+	if got["pressure_mb"] != 1018.0 {
+		t.Errorf("pressure_mb = %v, want 1018", got["pressure_mb"])
+	}
+	if got["dewpoint_C"] != 15.0 {
+		t.Errorf("dewpoint_C = %v, want 15", got["dewpoint_C"])
+	}
+
+	plain := decodeBody[map[string]any](t, getTemperature(t, "/temperature/01001000"))
+	for _, field := range []string{"pressure_mb", "dewpoint_C"} {
+		if _, ok := plain[field]; ok {
+			t.Errorf("%s present without extra=true", field)
+		}
+	}
+}
+
+func TestTemperatureExtraWithoutPressure(t *testing.T) {
+	current, err := os.ReadFile("testdata/weatherapi_current.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withoutPressure := bytes.Replace(current, []byte(`"pressure_mb":1018.0,`), nil, 1)
+	setupWithWeatherAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(withoutPressure)
+	})
+
+	rec := getTemperature(t, "/temperature/01001000?extra=true")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (body %s)", rec.Code, rec.Body.String())
+	}
+	got := decodeBody[map[string]any](t, rec)
+	if _, ok := got["pressure_mb"]; ok {
+		t.Errorf("pressure_mb = %v, want it omitted when the provider gives none", got["pressure_mb"])
+	}
+	if got["dewpoint_C"] != 15.0 {
+		t.Errorf("dewpoint_C = %v, want 15", got["dewpoint_C"])
+	}
+}
+
+func TestParseLocalTime(t *testing.T) {
+	tests := []struct {
+		localtime, tzID, want string
+	}{
+		{"2025-10-15 12:00", "America/Sao_Paulo", "2025-10-15T12:00:00-03:00"},
+		{"2025-10-15 9:05", "America/Manaus", "2025-10-15T09:05:00-04:00"},
+		{"2025-01-02 23:59", "America/Noronha", "2025-01-02T23:59:00-02:00"},
+	}
+	for _, tt := range tests {
+		got, err := parseLocalTime(tt.localtime, tt.tzID)
+		if err != nil {
+			t.Errorf("parseLocalTime(%q, %q): %v", tt.localtime, tt.tzID, err)
+			continue
+		}
+		if s := got.Format(time.RFC3339); s != tt.want {
+			t.Errorf("parseLocalTime(%q, %q) = %s, want %s", tt.localtime, tt.tzID, s, tt.want)
+		}
+	}
+}
+
+func TestParseLocalTimeErrors(t *testing.T) {
+	tests := []struct {
+		localtime, tzID, want string
+	}{
+		{"2025-10-15 12:00", "America/Atlantis", "unknown time zone"},
+		{"15/10/2025 12:00", "America/Sao_Paulo", "invalid local time"},
+	}
+	for _, tt := range tests {
+		if _, err := parseLocalTime(tt.localtime, tt.tzID); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseLocalTime(%q, %q) error = %v, want %q", tt.localtime, tt.tzID, err, tt.want)
+		}
+	}
+}
+
+func TestTemperatureConversions(t *testing.T) {
+	tests := []struct {
+		c, f, k float64
+	}{
+		{0, 32, 273.15},
+		{100, 212, 373.15},
+		{-40, -40, 233.15},
+		{25, 77, 298.15},
+		{37, 98.6, 310.15},
+		{-273.15, -459.67, 0},
+	}
+	for _, tt := range tests {
+		if got := celsiusToFahrenheit(tt.c); math.Abs(got-tt.f) > 1e-9 {
+			t.Errorf("celsiusToFahrenheit(%v) = %v, want %v", tt.c, got, tt.f)
+		}
+		if got := celsiusToKelvin(tt.c); math.Abs(got-tt.k) > 1e-9 {
+			t.Errorf("celsiusToKelvin(%v) = %v, want %v", tt.c, got, tt.k)
+		}
+	}
+}
+
+func TestRoundTemperature(t *testing.T) {
+	for in, want := range map[float64]float64{
+		298.15:  298.2,
+		77.0:    77,
+		22.14:   22.1,
+		-3.04:   -3,
+		-11.35:  -11.4,
+		36.6666: 36.7,
+	} {
+		if got := roundTemperature(in); got != want {
+			t.Errorf("roundTemperature(%v) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestDebugPrecisionBoundsRoundingError(t *testing.T) {
+	const rawC = 22.149
+	setupWithWeatherAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"location":{"name":"Sao Paulo","tz_id":"America/Sao_Paulo"},"current":{"temp_c":%v,"humidity":64}}`, rawC)
+	})
+
+	rec := getTemperature(t, "/temperature/01001000?debug=true")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (body %s)", rec.Code, rec.Body.String())
+	}
+	got := decodeBody[TemperatureResponse](t, rec)
+	if got.Precision == nil || *got.Precision != (Precision{Decimals: 1, MaxError: 0.05}) {
+		t.Fatalf("precision = %+v, want 1 decimal and max_error 0.05", got.Precision)
+	}
+	for name, c := range map[string]struct{ reported, exact float64 }{
+		"temp_C": {*got.TempC, rawC},
+		"temp_F": {*got.TempF, celsiusToFahrenheit(rawC)},
+		"temp_K": {*got.TempK, celsiusToKelvin(rawC)},
+	} {
+		if diff := math.Abs(c.reported - c.exact); diff > got.Precision.MaxError {
+			t.Errorf("%s = %v is %v away from %v, beyond max_error", name, c.reported, diff, c.exact)
+		}
+	}
+
+	if plain := decodeBody[TemperatureResponse](t, getTemperature(t, "/temperature/01001000")); plain.Precision != nil {
+		t.Errorf("precision = %+v without debug=true", plain.Precision)
+	}
+}
+
+func TestTemperatureAtFreezingPoint(t *testing.T) {
+	setupWithWeatherAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"location":{"name":"Sao Paulo","tz_id":"America/Sao_Paulo"},"current":{"temp_c":0.0,"humidity":90}}`)
+	})
+
+	rec := getTemperature(t, "/temperature/01001000")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 for 0°C (body %s)", rec.Code, rec.Body.String())
+	}
+	got := decodeBody[TemperatureResponse](t, rec)
+	if got.TempC == nil || *got.TempC != 0 || got.TempF == nil || *got.TempF != 32 {
+		t.Errorf("temp_C/temp_F = %v/%v, want 0/32", got.TempC, got.TempF)
+	}
+}
+
+func TestTemperatureMissingIsNotZero(t *testing.T) {
+	setupWithWeatherAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"location":{"name":"Sao Paulo","tz_id":"America/Sao_Paulo"},"current":{"humidity":90}}`)
+	})
+
+	assertError(t, getTemperature(t, "/temperature/01001000"), http.StatusInternalServerError, "weather_fetch_failed")
+}
+
+func TestTemperatureSkippedCity(t *testing.T) {
+	calls := setupWithWeatherAPI(t, nil, "WEATHER_SKIP_CITIES", "Manaus,são paulo")
+
+	rec := getTemperature(t, "/temperature/01001000")
+	assertError(t, rec, http.StatusUnprocessableEntity, "weather_unavailable_for_city")
+	if n := calls.Load(); n != 0 {
+		t.Errorf("WeatherAPI calls = %d, want 0 for a skipped city", n)
+	}
+}
+
+func TestTemperatureCityNotSkipped(t *testing.T) {
+	calls := setupWithWeatherAPI(t, nil, "WEATHER_SKIP_CITIES", "Manaus")
+
+	if rec := getTemperature(t, "/temperature/01001000"); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (body %s)", rec.Code, rec.Body.String())
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("WeatherAPI calls = %d, want 1", n)
+	}
+}
+
+func TestTemperatureNoTempAs200(t *testing.T) {
+	noTemperature := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"location":{"name":"Sao Paulo","tz_id":"America/Sao_Paulo"},"current":{"humidity":90}}`)
+	}
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		env     []string
+	}{
+		{"no temperature data", noTemperature, []string{"NO_TEMP_AS_200", "true"}},
+		{"skipped city", nil, []string{"NO_TEMP_AS_200", "true", "WEATHER_SKIP_CITIES", "São Paulo"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setupWithWeatherAPI(t, tc.handler, tc.env...)
+
+			rec := getTemperature(t, "/temperature/01001000")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body.String())
+			}
+			body := decodeBody[map[string]any](t, rec)
+			if body["city"] != "São Paulo" || body["temp_available"] != false {
+				t.Errorf("city/temp_available = %v/%v, want São Paulo/false", body["city"], body["temp_available"])
+			}
+			for _, field := range []string{"temp_C", "temp_F", "temp_K"} {
+				if v, ok := body[field]; !ok || v != nil {
+					t.Errorf("%s = %v (present %v), want null", field, v, ok)
+				}
+			}
+		})
+	}
+}
+
+func TestTemperatureNoTempDefaultIsError(t *testing.T) {
+	setupWithWeatherAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"location":{"name":"Sao Paulo","tz_id":"America/Sao_Paulo"},"current":{"humidity":90}}`)
+	}, "NO_TEMP_AS_200", "false")
+
+	rec := getTemperature(t, "/temperature/01001000")
+	assertError(t, rec, http.StatusInternalServerError, "weather_fetch_failed")
+	if _, ok := decodeBody[map[string]any](t, rec)["temp_available"]; ok {
+		t.Error("error response carries temp_available")
+	}
+}
+
+func TestTemperatureNoTempAs200KeepsOtherErrors(t *testing.T) {
+	setupWithWeatherAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"error":{"code":1006,"message":"No matching location found."}}`)
+	}, "NO_TEMP_AS_200", "true")
+
+	assertError(t, getTemperature(t, "/temperature/01001000"), http.StatusNotFound, "weather_not_found")
+}